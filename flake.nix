@@ -19,7 +19,7 @@
             pname = "mcpli";
             version = version;
             src = ./.;
-            vendorHash = "sha256-TcpuAFrIQhlGBVcQ45FiyIdoa8LXJ43nAzZzOIvu/g8=";
+            vendorHash = "sha256-DuwF37FA2Y4nE0LnOKmGD+BFI0vwanu3vrXRJb+g+J4=";
             ldflags = [ "-s" "-w" "-X github.com/juanibiapina/mcpli/internal/version.Version=${version}" ];
           };
         }
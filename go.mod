@@ -1,15 +1,27 @@
 module github.com/juanibiapina/mcpli
 
-go 1.25.5
+go 1.26.0
 
 require (
+	filippo.io/age v1.3.2
 	github.com/adrg/xdg v0.5.3
+	github.com/itchyny/gojq v0.12.19
 	github.com/spf13/cobra v1.10.2
-	golang.org/x/term v0.45.0
+	github.com/titanous/json5 v1.0.0
+	github.com/zalando/go-keyring v0.2.8
+	golang.org/x/net v0.60.0
+	golang.org/x/term v0.46.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	filippo.io/hpke v0.4.0 // indirect
+	github.com/danieljoos/wincred v1.2.3 // indirect
+	github.com/godbus/dbus/v5 v5.2.2 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/itchyny/timefmt-go v0.1.8 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/crypto v0.57.0 // indirect
+	golang.org/x/sys v0.48.0 // indirect
+	golang.org/x/text v0.42.0 // indirect
 )
@@ -0,0 +1,172 @@
+// Package bench runs requests concurrently and summarizes their latencies.
+package bench
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a benchmark run.
+type Result struct {
+	// Latencies of the successful requests.
+	Latencies []time.Duration
+	// Errors counts failed requests by kind.
+	Errors map[string]int
+	// Elapsed is the wall time of the run.
+	Elapsed time.Duration
+}
+
+// Requests returns the number of requests made.
+func (r *Result) Requests() int {
+	n := len(r.Latencies)
+	for _, count := range r.Errors {
+		n += count
+	}
+	return n
+}
+
+// Failed returns the number of failed requests.
+func (r *Result) Failed() int {
+	return r.Requests() - len(r.Latencies)
+}
+
+// Throughput returns the completed requests per second.
+func (r *Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests()) / r.Elapsed.Seconds()
+}
+
+// Worker makes one request. Workers are not shared between goroutines.
+type Worker func() error
+
+// Run makes requests with the given concurrency. newWorker is called once per
+// goroutine, e.g. to open a session; if it fails, the run is aborted. classify
+// names the kind of a request error for the breakdown.
+func Run(requests, concurrency int, newWorker func() (Worker, error), classify func(error) string) (*Result, error) {
+	concurrency = max(1, min(concurrency, requests))
+
+	workers := make([]Worker, concurrency)
+	for i := range workers {
+		w, err := newWorker()
+		if err != nil {
+			return nil, err
+		}
+		workers[i] = w
+	}
+
+	result := &Result{Errors: map[string]int{}}
+	var mu sync.Mutex
+	jobs := make(chan struct{}, requests)
+	for i := 0; i < requests; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			for range jobs {
+				requestStart := time.Now()
+				err := worker()
+				latency := time.Since(requestStart)
+
+				mu.Lock()
+				if err != nil {
+					result.Errors[classify(err)]++
+				} else {
+					result.Latencies = append(result.Latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}(worker)
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+
+	return result, nil
+}
+
+// Stats summarizes latencies.
+type Stats struct {
+	Count int
+	Min   time.Duration
+	Avg   time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Summarize computes latency statistics. Percentiles use the nearest rank.
+func Summarize(latencies []time.Duration) Stats {
+	if len(latencies) == 0 {
+		return Stats{}
+	}
+	sorted := sortedCopy(latencies)
+
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	return Stats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Avg:   total / time.Duration(len(sorted)),
+		P50:   percentile(sorted, 50),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(0, rank-1)]
+}
+
+func sortedCopy(latencies []time.Duration) []time.Duration {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// Bucket is a histogram bucket of latencies from Lower up to Upper. The last
+// bucket includes Upper.
+type Bucket struct {
+	Lower time.Duration
+	Upper time.Duration
+	Count int
+}
+
+// Histogram splits the range of latencies into n equal buckets.
+func Histogram(latencies []time.Duration, n int) []Bucket {
+	if len(latencies) == 0 || n <= 0 {
+		return nil
+	}
+	sorted := sortedCopy(latencies)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	width := (hi - lo) / time.Duration(n)
+	if width <= 0 {
+		return []Bucket{{Lower: lo, Upper: hi, Count: len(sorted)}}
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].Lower = lo + time.Duration(i)*width
+		buckets[i].Upper = lo + time.Duration(i+1)*width
+	}
+	buckets[n-1].Upper = hi
+
+	for _, l := range sorted {
+		i := min(int((l-lo)/width), n-1)
+		buckets[i].Count++
+	}
+	return buckets
+}
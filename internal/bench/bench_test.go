@@ -0,0 +1,99 @@
+package bench
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var workers, calls atomic.Int32
+	newWorker := func() (Worker, error) {
+		workers.Add(1)
+		return func() error {
+			if calls.Add(1)%4 == 0 {
+				return errors.New("boom")
+			}
+			return nil
+		}, nil
+	}
+
+	result, err := Run(20, 3, newWorker, func(err error) string { return err.Error() })
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if workers.Load() != 3 {
+		t.Errorf("created %d workers, want 3", workers.Load())
+	}
+	if result.Requests() != 20 || result.Failed() != 5 || result.Errors["boom"] != 5 {
+		t.Errorf("requests %d, failed %d, errors %v; want 20, 5, boom=5", result.Requests(), result.Failed(), result.Errors)
+	}
+}
+
+func TestRun_ConcurrencyCappedByRequests(t *testing.T) {
+	var workers atomic.Int32
+	newWorker := func() (Worker, error) {
+		workers.Add(1)
+		return func() error { return nil }, nil
+	}
+
+	if _, err := Run(2, 8, newWorker, nil); err != nil {
+		t.Fatal(err)
+	}
+	if workers.Load() != 2 {
+		t.Errorf("created %d workers, want 2", workers.Load())
+	}
+}
+
+func TestRun_WorkerSetupError(t *testing.T) {
+	newWorker := func() (Worker, error) { return nil, errors.New("initialize failed") }
+	if _, err := Run(10, 2, newWorker, nil); err == nil {
+		t.Error("expected setup error")
+	}
+}
+
+func ms(values ...int) []time.Duration {
+	var d []time.Duration
+	for _, v := range values {
+		d = append(d, time.Duration(v)*time.Millisecond)
+	}
+	return d
+}
+
+func TestSummarize(t *testing.T) {
+	latencies := ms(5, 1, 4, 2, 3, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 100)
+	stats := Summarize(latencies)
+
+	want := Stats{
+		Count: 20,
+		Min:   1 * time.Millisecond,
+		Avg:   14500 * time.Microsecond,
+		P50:   10 * time.Millisecond,
+		P95:   19 * time.Millisecond,
+		P99:   100 * time.Millisecond,
+		Max:   100 * time.Millisecond,
+	}
+	if stats != want {
+		t.Errorf("Summarize() = %+v, want %+v", stats, want)
+	}
+
+	if (Summarize(nil) != Stats{}) {
+		t.Error("Summarize(nil) should be empty")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	buckets := Histogram(ms(0, 1, 2, 5, 9, 10), 2)
+	if len(buckets) != 2 || buckets[0].Count != 3 || buckets[1].Count != 3 {
+		t.Errorf("Histogram() = %+v, want counts 3 and 3", buckets)
+	}
+	if buckets[1].Upper != 10*time.Millisecond {
+		t.Errorf("last bucket upper bound = %s, want 10ms", buckets[1].Upper)
+	}
+
+	same := Histogram(ms(3, 3, 3), 5)
+	if len(same) != 1 || same[0].Count != 3 {
+		t.Errorf("Histogram() of equal latencies = %+v, want one bucket", same)
+	}
+}
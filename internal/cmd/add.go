@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/juanibiapina/mcpli/internal/config"
 	"github.com/juanibiapina/mcpli/internal/mcp"
@@ -12,7 +11,27 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var addHeaders []string
+var (
+	addHeaders           []string
+	addScopes            []string
+	addDevice            bool
+	addClientCredentials bool
+	addClientID          string
+	addClientSecret      string
+	addRedirectHost      string
+	addRedirectPort      int
+	addRedirectPortRange string
+	addIdentity          string
+	addCAFile            string
+	addClientCert        string
+	addClientKey         string
+	addInsecure          bool
+	addMinTLSVersion     string
+	addPinnedSPKI        []string
+	addProxy             string
+	addNoProxy           string
+	addRefreshAfter      string
+)
 
 var addCmd = &cobra.Command{
 	Use:   "add <name> <url>",
@@ -25,13 +44,65 @@ These will be expanded at runtime when invoking tools.
 Examples:
   mcpli add knuspr https://mcp.knuspr.de/mcp/ \
     --header "rhl-email: \${ROHLIK_USERNAME}" \
-    --header "rhl-pass: \${ROHLIK_PASSWORD}"`,
+    --header "rhl-pass: \${ROHLIK_PASSWORD}"
+
+If the server requires OAuth, the requested scopes default to those announced
+by the server. Use --scope to request specific scopes instead:
+  mcpli add glean https://example.glean.com/mcp/default --scope search --scope chat
+
+On machines without a browser, use --device to authenticate with a code
+entered on another device.
+
+If the authorization server does not support dynamic client registration,
+pass a client registered with it using --client-id, --client-secret and
+--redirect-port. The client id may also be the URL of a client ID metadata
+document.
+
+Servers behind a private CA or requiring mutual TLS:
+  mcpli add internal https://mcp.internal.example.com/mcp \
+    --ca-file /etc/ssl/internal-ca.pem \
+    --client-cert ~/.certs/me.pem --client-key ~/.certs/me-key.pem
+
+Servers reached through a proxy, or listening on a unix domain socket:
+  mcpli add remote https://mcp.example.com/mcp --proxy socks5://127.0.0.1:1080
+  mcpli add local unix:///run/mcp.sock/mcp
+
+To refresh the cached tools automatically when they are older than a day:
+  mcpli add knuspr https://mcp.knuspr.de/mcp/ --refresh-after 24h
+
+To use a different account than another server with the same URL, store the
+credentials under a named identity:
+  mcpli add glean-personal https://example.glean.com/mcp/default --identity personal
+
+Service accounts can use the client credentials grant. Reference the secret
+through an environment variable so only the reference is stored:
+  mcpli add internal https://mcp.internal.example.com/mcp \
+    --oauth-client-credentials --client-id my-service \
+    --client-secret '\${MCP_CLIENT_SECRET}'`,
 	Args: cobra.ExactArgs(2),
 	RunE: runAdd,
 }
 
 func init() {
 	addCmd.Flags().StringArrayVarP(&addHeaders, "header", "H", nil, "HTTP header in 'key: value' format (can be repeated)")
+	addCmd.Flags().StringArrayVar(&addScopes, "scope", nil, "OAuth scope to request (can be repeated)")
+	addCmd.Flags().BoolVar(&addDevice, "device", false, "Use the OAuth device authorization grant instead of a browser callback")
+	addCmd.Flags().BoolVar(&addClientCredentials, "oauth-client-credentials", false, "Authenticate with the OAuth client credentials grant")
+	addCmd.Flags().StringVar(&addClientID, "client-id", "", "Pre-registered OAuth client id (or client ID metadata document URL)")
+	addCmd.Flags().StringVar(&addClientSecret, "client-secret", "", "OAuth client secret (use ${VAR} to keep it out of the config)")
+	addCmd.Flags().StringVar(&addRedirectHost, "redirect-host", "", "Loopback address of the OAuth redirect URI: 127.0.0.1 (default) or ::1")
+	addCmd.Flags().IntVar(&addRedirectPort, "redirect-port", 0, "Exact port of the OAuth redirect URI, for clients registered with a fixed redirect URI")
+	addCmd.Flags().StringVar(&addRedirectPortRange, "redirect-port-range", "", "Range of ports for the OAuth redirect URI, e.g. 50000-50100 (default: any free port)")
+	addCmd.Flags().StringVar(&addCAFile, "ca-file", "", "PEM bundle of additional certificate authorities to trust")
+	addCmd.Flags().StringVar(&addClientCert, "client-cert", "", "PEM client certificate for mutual TLS")
+	addCmd.Flags().StringVar(&addClientKey, "client-key", "", "PEM private key of the client certificate")
+	addCmd.Flags().BoolVar(&addInsecure, "insecure-skip-verify", false, "Disable TLS certificate verification (dangerous: only for testing)")
+	addCmd.Flags().StringVar(&addMinTLSVersion, "min-tls-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	addCmd.Flags().StringArrayVar(&addPinnedSPKI, "pin-spki", nil, "Base64 SHA-256 hash of a trusted certificate public key (can be repeated)")
+	addCmd.Flags().StringVar(&addProxy, "proxy", "", "Proxy URL for the server: http://, https:// or socks5:// (default: HTTP_PROXY/HTTPS_PROXY)")
+	addCmd.Flags().StringVar(&addNoProxy, "no-proxy", "", "Comma-separated hosts that bypass the proxy (default: NO_PROXY)")
+	addCmd.Flags().StringVar(&addRefreshAfter, "refresh-after", "", "Refresh the cached tools in the background when they are older than this, e.g. 24h")
+	addCmd.Flags().StringVar(&addIdentity, "identity", "", "Store OAuth credentials under this identity, to use another account than other servers with the same URL")
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
@@ -59,37 +130,93 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server %q already exists (use 'mcpli update %s' to refresh)", name, name)
 	}
 
+	// OAuth settings requested on the command line are kept in the server
+	// config so re-authentication uses the same grant.
+	server := &config.Server{
+		URL:                url,
+		Headers:            headers,
+		Scopes:             splitScopes(addScopes),
+		ClientID:           addClientID,
+		ClientSecret:       addClientSecret,
+		RedirectHost:       addRedirectHost,
+		RedirectPort:       addRedirectPort,
+		RedirectPortRange:  addRedirectPortRange,
+		Identity:           addIdentity,
+		CAFile:             addCAFile,
+		ClientCert:         addClientCert,
+		ClientKey:          addClientKey,
+		InsecureSkipVerify: addInsecure,
+		MinTLSVersion:      addMinTLSVersion,
+		PinnedSPKI:         addPinnedSPKI,
+		Proxy:              addProxy,
+		NoProxy:            addNoProxy,
+	}
+	if addRefreshAfter != "" {
+		if server.RefreshAfter, err = parseRefreshAfter(addRefreshAfter); err != nil {
+			return err
+		}
+	}
+	if addClientCredentials {
+		if addClientID == "" {
+			return fmt.Errorf("--oauth-client-credentials requires --client-id")
+		}
+		server.GrantType = oauth.GrantClientCredentials
+	}
+
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+
 	// Create client with expanded headers for the initial connection
 	expandedHeaders := make(map[string]string)
 	for k, v := range headers {
 		expandedHeaders[k] = config.ExpandEnv(v)
 	}
-	client := mcp.NewClient(url, expandedHeaders)
+	client := newClient(url, expandedHeaders, rt)
+
+	// authenticate runs the OAuth flow and recreates the client with the new token
+	authenticate := func(challenge string) error {
+		opts := authOptions(server)
+		opts.Challenge = challenge
+		opts.Device = addDevice
+		entry, err := oauth.Authenticate(oauthClient(rt), url, opts)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+		server.OAuth = true
+		server.Issuer = entry.Issuer
+
+		token, err := oauth.GetValidToken(oauthClient(rt), url, server.Identity, server.Issuer)
+		if err != nil {
+			return fmt.Errorf("failed to get token after authentication: %w", err)
+		}
+		expandedHeaders["Authorization"] = "Bearer " + token
+		client = newClient(url, expandedHeaders, rt)
+		return nil
+	}
+
+	// Machine-to-machine clients don't wait for a 401 challenge
+	if server.GrantType == oauth.GrantClientCredentials {
+		if err := authenticate(""); err != nil {
+			return err
+		}
+	}
 
 	// Initialize connection
 	fmt.Printf("Connecting to %s...\n", url)
-	useOAuth := false
 	initResult, err := client.Initialize()
 	if err != nil {
 		// Check if server requires OAuth
 		var unauthorizedErr *mcp.UnauthorizedError
-		if !errors.As(err, &unauthorizedErr) {
+		if !errors.As(err, &unauthorizedErr) || server.OAuth {
 			return fmt.Errorf("failed to initialize: %w", err)
 		}
 
-		// Server returned 401, try OAuth flow
-		if err := oauth.Authenticate(url); err != nil {
-			return fmt.Errorf("failed to authenticate: %w", err)
-		}
-		useOAuth = true
-
-		// Retry with OAuth token
-		token, err := oauth.GetValidToken(url)
-		if err != nil {
-			return fmt.Errorf("failed to get token after authentication: %w", err)
+		// Server returned 401, try OAuth flow and retry with the token
+		if err := authenticate(unauthorizedErr.WWWAuthenticate); err != nil {
+			return err
 		}
-		expandedHeaders["Authorization"] = "Bearer " + token
-		client = mcp.NewClient(url, expandedHeaders)
 
 		initResult, err = client.Initialize()
 		if err != nil {
@@ -106,29 +233,9 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("Found %d tools\n", len(toolsResult.Tools))
 
-	// Convert tools to config format
-	tools := make([]config.Tool, len(toolsResult.Tools))
-	for i, t := range toolsResult.Tools {
-		tools[i] = config.Tool{
-			Name:        t.Name,
-			Description: t.Description,
-			InputSchema: t.InputSchema,
-		}
-	}
-
 	// Save server config (with unexpanded headers)
-	cfg.Servers[name] = &config.Server{
-		URL:             url,
-		Headers:         headers,
-		OAuth:           useOAuth,
-		ProtocolVersion: initResult.ProtocolVersion,
-		ServerInfo: config.ServerInfo{
-			Name:    initResult.ServerInfo.Name,
-			Version: initResult.ServerInfo.Version,
-		},
-		Tools:     tools,
-		UpdatedAt: time.Now(),
-	}
+	applyTools(server, initResult, configTools(toolsResult.Tools))
+	cfg.Servers[name] = server
 
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
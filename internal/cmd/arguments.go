@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/toolargs"
+	"github.com/spf13/cobra"
+)
+
+// fieldFlag is a flag generated for a property of a tool's input schema.
+type fieldFlag struct {
+	prop  toolargs.Property
+	value string
+}
+
+func (f *fieldFlag) String() string {
+	return f.value
+}
+
+func (f *fieldFlag) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+// Type names the value in the help; "bool" makes pflag show a boolean
+// flag without a value.
+func (f *fieldFlag) Type() string {
+	switch f.prop.Type {
+	case "boolean":
+		return "bool"
+	case "":
+		return "value"
+	}
+	return f.prop.Type
+}
+
+// addFieldFlags adds a flag for each top-level property of a tool's input
+// schema, and returns the names of properties that have no flag because
+// mcpli's own flags use them (see checkFlagConflicts). Properties whose
+// name can't be a flag name get no flag either. Both can still be given in
+// the JSON arguments.
+func addFieldFlags(cmd *cobra.Command, tool config.Tool) ([]*fieldFlag, []string) {
+	var fields []*fieldFlag
+	var conflicts []string
+	for _, prop := range toolargs.Properties(tool.InputSchema) {
+		name := prop.Name
+		if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, "= \t") {
+			continue
+		}
+		if name == "help" || cmd.Flags().Lookup(name) != nil || rootCmd.PersistentFlags().Lookup(name) != nil {
+			conflicts = append(conflicts, name)
+			continue
+		}
+
+		usage := strings.Join(strings.Fields(strings.ReplaceAll(prop.Description, "`", "'")), " ")
+		usage = truncateDescription(usage, 60)
+		if prop.Required {
+			usage = strings.TrimPrefix(usage+" (required)", " ")
+		}
+
+		field := &fieldFlag{prop: prop}
+		flag := cmd.Flags().VarPF(field, name, "", usage)
+		if prop.Type == "boolean" {
+			flag.NoOptDefVal = "true"
+		}
+		fields = append(fields, field)
+	}
+	return fields, conflicts
+}
+
+// checkFlagConflicts fails if one of mcpli's flags is given whose name is
+// also a property of the tool, unless the arguments set the property: it
+// isn't clear otherwise which one is meant.
+func checkFlagConflicts(cmd *cobra.Command, conflicts []string, arguments json.RawMessage) error {
+	var given map[string]json.RawMessage
+	json.Unmarshal(arguments, &given)
+	for _, name := range conflicts {
+		if _, ok := given[name]; ok {
+			continue
+		}
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s is an mcpli flag, but %q is also a property of this tool; give the property in the arguments, e.g. '{\"%s\": ...}'", name, name, name)
+		}
+	}
+	return nil
+}
+
+// readArguments returns the JSON arguments of a tool call, given as the
+// argument, read from stdin with "-", from a file with "@file" or
+// --arg-file, and with the properties set by generated flags merged in.
+// Arguments can be written as JSON, JSON5 or YAML (see --input-format).
+// Flag values can be read from a file or stdin with "@file" and "@-".
+func readArguments(cmd *cobra.Command, args []string, tool config.Tool, fields []*fieldFlag) (json.RawMessage, error) {
+	loader := toolargs.NewLoader(os.Stdin)
+
+	source := ""
+	if len(args) > 0 {
+		source = args[0]
+		if source == "-" {
+			source = "@-"
+		}
+	}
+	if argFile, _ := cmd.Flags().GetString("arg-file"); argFile != "" {
+		if source != "" {
+			return nil, fmt.Errorf("give the arguments either as an argument or with --arg-file, not both")
+		}
+		source = "@" + argFile
+		if argFile == "-" {
+			source = "@-"
+		}
+	}
+
+	var arguments json.RawMessage
+	if source != "" {
+		data, loaded, err := loader.Load(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read arguments: %w", err)
+		}
+		if loaded && strings.TrimSpace(string(data)) == "" {
+			return nil, fmt.Errorf("no arguments in %s", strings.TrimPrefix(source, "@"))
+		}
+		format, _ := cmd.Flags().GetString("input-format")
+		if arguments, err = toolargs.Parse(data, format, tool.InputSchema); err != nil {
+			return nil, err
+		}
+		if format != toolargs.FormatJSON && !json.Valid(data) && !strings.HasPrefix(string(arguments), "{") {
+			return nil, fmt.Errorf("arguments must be a mapping of argument names to values, e.g. 'query: milk'")
+		}
+	}
+
+	values := map[string]interface{}{}
+	changed := false
+	for _, field := range fields {
+		if !cmd.Flags().Changed(field.prop.Name) {
+			continue
+		}
+		value, err := loader.Value(field.prop, field.value)
+		if err != nil {
+			return nil, err
+		}
+		values[field.prop.Name] = value
+		changed = true
+	}
+	if !changed {
+		return arguments, nil
+	}
+
+	merged := map[string]interface{}{}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &merged); err != nil {
+			return nil, fmt.Errorf("arguments must be a JSON object to be combined with flags")
+		}
+	}
+	for name, value := range values {
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var searchTool = config.Tool{
+	Name: "search",
+	InputSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string"},
+			"limit": {"type": "integer"},
+			"structured": {"type": "boolean"}
+		},
+		"required": ["query"]
+	}`),
+}
+
+func TestToolCommand_Flags(t *testing.T) {
+	cmd := createToolCommand("srv", &config.Server{}, searchTool)
+	if flag := cmd.Flags().Lookup("query"); flag == nil || flag.Shorthand != "q" {
+		t.Errorf("--query flag = %v, want mcpli's query flag", flag)
+	}
+	if _, ok := cmd.Flags().Lookup("limit").Value.(*fieldFlag); !ok {
+		t.Error("--limit is not the property's flag")
+	}
+}
+
+func TestAddFieldFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	addOutputFlags(cmd, output.Raw)
+	cmd.Flags().Bool("structured", false, "")
+	fields, conflicts := addFieldFlags(cmd, searchTool)
+	if strings.Join(conflicts, ",") != "query,structured" {
+		t.Errorf("conflicts = %v, want [query structured]", conflicts)
+	}
+	if len(fields) != 1 || fields[0].prop.Name != "limit" {
+		t.Errorf("fields = %v, want [limit]", fields)
+	}
+}
+
+func TestCheckFlagConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []string
+		arguments string
+		wantErr   bool
+	}{
+		{"flag not given", []string{"--limit", "5"}, `{"query": "milk"}`, false},
+		{"property in the arguments", []string{"-q", ".content"}, `{"query": "milk"}`, false},
+		{"property missing", []string{"--query", "milk"}, `{}`, true},
+		{"shorthand with property missing", []string{"-q", ".content"}, `{}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addOutputFlags(cmd, output.Raw)
+			cmd.Flags().Bool("structured", false, "")
+			_, conflicts := addFieldFlags(cmd, searchTool)
+			if err := cmd.ParseFlags(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkFlagConflicts(cmd, conflicts, json.RawMessage(tt.arguments))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkFlagConflicts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), `"query" is also a property`) {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
@@ -1,21 +1,713 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mcp"
 	"github.com/juanibiapina/mcpli/internal/oauth"
+	"github.com/juanibiapina/mcpli/internal/terminal"
+	"github.com/spf13/cobra"
 )
 
-// resolveHeaders returns the headers for a server, including an OAuth token if applicable.
-func resolveHeaders(serverName string, server *config.Server) (map[string]string, error) {
+var (
+	authLoginDevice            bool
+	authLoginClientID          string
+	authLoginClientSecret      string
+	authLoginRedirectHost      string
+	authLoginRedirectPort      int
+	authLoginRedirectPortRange string
+	authLoginIdentity          string
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage OAuth credentials",
+	Long: `Manage OAuth credentials for configured servers.
+
+Examples:
+  mcpli auth status                 # Show credentials for all servers
+  mcpli auth login glean            # Authenticate in the browser
+  mcpli auth login glean --device   # Authenticate from a headless machine
+  mcpli auth login glean --identity personal  # Log in with another account
+  mcpli auth use glean              # Switch back to the default account
+  mcpli auth refresh glean          # Refresh the access token now
+  mcpli auth logout glean           # Revoke and remove the tokens
+  mcpli auth migrate keyring        # Move credentials to the OS keyring
+  curl -H "Authorization: Bearer $(mcpli auth token glean)" ...`,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status [server]",
+	Short: "Show OAuth credentials",
+	Long: `Show the OAuth credentials of all servers, or of a specific server: the
+client id, access token expiry, granted scopes and whether the token can be
+refreshed.
+
+Examples:
+  mcpli auth status
+  mcpli auth status glean`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAuthStatus,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout <server>",
+	Short: "Remove the tokens for a server",
+	Long: `Remove the OAuth tokens for a server. If the authorization server advertises
+a revocation endpoint, the tokens are revoked there first (RFC 7009).
+
+The client registration is kept, so the next login doesn't register a new client.
+
+Example:
+  mcpli auth logout glean`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthLogout,
+}
+
+var authRefreshCmd = &cobra.Command{
+	Use:   "refresh <server>",
+	Short: "Refresh the access token for a server",
+	Long: `Obtain a new access token for a server now, using the refresh token (or the
+client credentials grant), even if the current token has not expired.
+
+Example:
+  mcpli auth refresh glean`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthRefresh,
+}
+
+var authTokenCmd = &cobra.Command{
+	Use:   "token <server>",
+	Short: "Print a valid access token for a server",
+	Long: `Print a valid access token for a server, refreshing it if it has expired.
+Only the token is printed, so it can be used in other commands.
+
+Example:
+  curl -H "Authorization: Bearer $(mcpli auth token glean)" https://api.example.com/`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthToken,
+}
+
+var authMigrateCmd = &cobra.Command{
+	Use:   "migrate <file|keyring|age>",
+	Short: "Move the stored credentials to another auth store",
+	Long: `Move all OAuth credentials to another auth store and select it in the config.
+
+Auth stores:
+  file     Plaintext JSON file readable only by the user (default)
+  keyring  OS secret service: Secret Service over D-Bus on Linux, Keychain on
+           macOS, Credential Manager on Windows
+  age      File encrypted with a passphrase using age. The passphrase is read
+           from MCPLI_AUTH_PASSPHRASE or prompted for on the terminal.
+
+Examples:
+  mcpli auth migrate keyring
+  mcpli auth migrate age`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthMigrate,
+}
+
+var authUseCmd = &cobra.Command{
+	Use:   "use <server> [identity]",
+	Short: "Select the identity used for a server",
+	Long: `Select which of the stored identities (accounts) is used for a server.
+Without an identity, the default identity is selected. Credentials of other
+identities are kept, so switching back doesn't require logging in again.
+
+Examples:
+  mcpli auth use glean personal
+  mcpli auth use glean`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runAuthUse,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login <server>",
+	Short: "Authenticate with a server",
+	Long: `Run the OAuth flow for a configured server and store the resulting tokens.
+
+By default a browser is opened and the authorization code is received on a
+local callback listening on a free loopback port. With --device, the device
+authorization grant (RFC 8628) is used instead: mcpli prints a URL and a code
+to enter on any other device, which works on remote machines and CI runners
+without a browser.
+
+For authorization servers without dynamic client registration, pass the
+client registered with the server. The client id may also be the URL of a
+client ID metadata document. The client settings are saved with the server.
+
+With --identity, the credentials are stored under a named identity and the
+server is switched to it, so several accounts can be logged in to the same
+server. Use 'mcpli auth use' to switch between them.
+
+Examples:
+  mcpli auth login glean
+  mcpli auth login glean --device
+  mcpli auth login glean --identity personal
+  mcpli auth login github --client-id Iv1.abc123 --client-secret '\${GITHUB_CLIENT_SECRET}' --redirect-port 8765`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthLogin,
+}
+
+func init() {
+	authLoginCmd.Flags().BoolVar(&authLoginDevice, "device", false, "Use the device authorization grant instead of a browser callback")
+	authLoginCmd.Flags().StringVar(&authLoginClientID, "client-id", "", "Pre-registered OAuth client id (or client ID metadata document URL)")
+	authLoginCmd.Flags().StringVar(&authLoginClientSecret, "client-secret", "", "OAuth client secret (use ${VAR} to keep it out of the config)")
+	authLoginCmd.Flags().StringVar(&authLoginRedirectHost, "redirect-host", "", "Loopback address of the redirect URI: 127.0.0.1 (default) or ::1")
+	authLoginCmd.Flags().IntVar(&authLoginRedirectPort, "redirect-port", 0, "Exact port of the redirect URI, for clients registered with a fixed redirect URI")
+	authLoginCmd.Flags().StringVar(&authLoginRedirectPortRange, "redirect-port-range", "", "Range of ports for the redirect URI, e.g. 50000-50100 (default: any free port)")
+	authLoginCmd.Flags().StringVar(&authLoginIdentity, "identity", "", "Store the credentials under this identity and use it for the server")
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authRefreshCmd)
+	authCmd.AddCommand(authTokenCmd)
+	authCmd.AddCommand(authMigrateCmd)
+	authCmd.AddCommand(authUseCmd)
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store, err := oauth.LoadStore()
+	if err != nil {
+		return fmt.Errorf("failed to load auth store: %w", err)
+	}
+
+	var names []string
+	if len(args) > 0 {
+		if _, exists := cfg.Servers[args[0]]; !exists {
+			return fmt.Errorf("server %q not found", args[0])
+		}
+		names = args
+	} else {
+		for name, server := range cfg.Servers {
+			if server.OAuth || len(store.Identities(server.URL)) > 0 {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	if len(names) == 0 {
+		fmt.Println("No servers use OAuth.")
+		return nil
+	}
+
+	for i, name := range names {
+		if i > 0 {
+			fmt.Println()
+		}
+		server := cfg.Servers[name]
+		printAuthStatus(name, server, store)
+	}
+	return nil
+}
+
+// printAuthStatus prints the OAuth credentials of one server.
+func printAuthStatus(name string, server *config.Server, store *oauth.AuthStore) {
+	fmt.Println(name)
+	fmt.Printf("  URL:           %s\n", server.URL)
+	fmt.Printf("  Identity:      %s\n", identityName(server.Identity))
+	if identities := store.Identities(server.URL); len(identities) > 1 {
+		names := make([]string, len(identities))
+		for i, identity := range identities {
+			names[i] = identityName(identity)
+		}
+		fmt.Printf("  Stored:        %s\n", strings.Join(names, ", "))
+	}
+
+	entry := store.Entry(server.URL, server.Identity, server.Issuer)
+	if entry == nil {
+		fmt.Println("  Status:        not logged in")
+		return
+	}
+
+	if entry.Issuer != "" {
+		fmt.Printf("  Issuer:        %s\n", entry.Issuer)
+	}
+	if entry.ClientID != "" {
+		fmt.Printf("  Client ID:     %s\n", entry.ClientID)
+	}
+
+	switch {
+	case entry.AccessToken == "":
+		fmt.Println("  Access token:  none (not logged in)")
+	case entry.IsExpired():
+		fmt.Printf("  Access token:  expired at %s\n", entry.ExpiresAt.Local().Format(time.DateTime))
+	default:
+		remaining := time.Until(entry.ExpiresAt).Round(time.Minute)
+		fmt.Printf("  Access token:  valid until %s (in %s)\n", entry.ExpiresAt.Local().Format(time.DateTime), remaining)
+	}
+
+	if entry.Scope != "" {
+		fmt.Printf("  Scopes:        %s\n", entry.Scope)
+	}
+
+	switch {
+	case entry.GrantType == oauth.GrantClientCredentials:
+		fmt.Println("  Refresh:       automatic (client credentials)")
+	case entry.RefreshToken != "":
+		fmt.Println("  Refresh:       available")
+	default:
+		fmt.Println("  Refresh:       unavailable (log in again when the token expires)")
+	}
+}
+
+// identityName returns the display name of an identity.
+func identityName(identity string) string {
+	if identity == "" {
+		return "default"
+	}
+	return identity
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := oauth.Logout(oauthClient(rt), server.URL, server.Identity, server.Issuer)
+	var revocationErr *oauth.RevocationError
+	if errors.As(err, &revocationErr) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if err != nil {
+		return err
+	}
+	if revoked {
+		fmt.Println("Tokens revoked at the authorization server")
+	}
+
+	fmt.Printf("Logged out of %q\n", name)
+	return nil
+}
+
+func runAuthRefresh(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+
+	entry, err := oauth.Refresh(oauthClient(rt), server.URL, server.Identity, server.Issuer)
+	if err != nil {
+		return fmt.Errorf("%w\nRun 'mcpli auth login %s' to re-authenticate", err, name)
+	}
+
+	fmt.Printf("Access token for %q refreshed, valid until %s\n", name, entry.ExpiresAt.Local().Format(time.DateTime))
+	return nil
+}
+
+func runAuthToken(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+
+	token, err := oauth.GetValidToken(oauthClient(rt), server.URL, server.Identity, server.Issuer)
+	if err != nil {
+		return fmt.Errorf("%w\nRun 'mcpli auth login %s' to authenticate", err, name)
+	}
+
+	fmt.Println(token)
+	return nil
+}
+
+func runAuthMigrate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	from, err := oauth.NewBackend(cfg.AuthStore, authPassphrase)
+	if err != nil {
+		return err
+	}
+	to, err := oauth.NewBackend(args[0], authPassphrase)
+	if err != nil {
+		return err
+	}
+	if from.Name() == to.Name() {
+		return fmt.Errorf("credentials are already stored in %s", to.Name())
+	}
+
+	n, err := oauth.Migrate(from, to, func() error {
+		cfg.AuthStore = to.Name()
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Moved %d credential(s) from %s to %s\n", n, from.Name(), to.Name())
+	return nil
+}
+
+// configureAuthStore selects the auth store backend from the config.
+func configureAuthStore() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	backend, err := oauth.NewBackend(cfg.AuthStore, authPassphrase)
+	if err != nil {
+		return err
+	}
+	oauth.SetBackend(backend)
+	return nil
+}
+
+// authPassphrase returns the passphrase of the encrypted auth store from
+// MCPLI_AUTH_PASSPHRASE, or prompts for it on the terminal.
+func authPassphrase(confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv("MCPLI_AUTH_PASSPHRASE"); ok {
+		return passphrase, nil
+	}
+	if !terminal.IsInteractive() {
+		return "", fmt.Errorf("auth store is encrypted: set MCPLI_AUTH_PASSPHRASE or run in a terminal")
+	}
+
+	passphrase, err := terminal.ReadPassword("Auth store passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := terminal.ReadPassword("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// loadServer loads the config and returns the named server.
+func loadServer(name string) (*config.Server, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	server, exists := cfg.Servers[name]
+	if !exists {
+		return nil, fmt.Errorf("server %q not found", name)
+	}
+
+	return server, nil
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Find server
+	server, exists := cfg.Servers[name]
+	if !exists {
+		return fmt.Errorf("server %q not found", name)
+	}
+
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+
+	// Client settings given on the command line replace the saved ones
+	if cmd.Flags().Changed("client-id") {
+		server.ClientID = authLoginClientID
+		server.ClientSecret = authLoginClientSecret
+	} else if cmd.Flags().Changed("client-secret") {
+		server.ClientSecret = authLoginClientSecret
+	}
+	if cmd.Flags().Changed("redirect-host") {
+		server.RedirectHost = authLoginRedirectHost
+	}
+	if cmd.Flags().Changed("redirect-port") {
+		server.RedirectPort = authLoginRedirectPort
+	}
+	if cmd.Flags().Changed("redirect-port-range") {
+		server.RedirectPortRange = authLoginRedirectPortRange
+	}
+	if cmd.Flags().Changed("identity") {
+		server.Identity = authLoginIdentity
+	}
+
+	opts := authOptions(server)
+	opts.Device = authLoginDevice
+	entry, err := oauth.Authenticate(oauthClient(rt), server.URL, opts)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	server.OAuth = true
+	server.Issuer = entry.Issuer
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if server.Identity != "" {
+		fmt.Printf("Logged in to %q as identity %q\n", name, server.Identity)
+	} else {
+		fmt.Printf("Logged in to %q\n", name)
+	}
+	return nil
+}
+
+func runAuthUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	identity := ""
+	if len(args) > 1 {
+		identity = args[1]
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	server, exists := cfg.Servers[name]
+	if !exists {
+		return fmt.Errorf("server %q not found", name)
+	}
+
+	store, err := oauth.LoadStore()
+	if err != nil {
+		return fmt.Errorf("failed to load auth store: %w", err)
+	}
+	entry := store.Entry(server.URL, identity, server.Issuer)
+	if entry == nil {
+		entry = store.Entry(server.URL, identity, "")
+	}
+	if entry == nil {
+		login := "mcpli auth login " + name
+		if identity != "" {
+			login += " --identity " + identity
+		}
+		return fmt.Errorf("no credentials stored for identity %q of %q\nRun '%s' to log in", identityName(identity), name, login)
+	}
+
+	server.Identity = identity
+	server.Issuer = entry.Issuer
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("Server %q now uses identity %q\n", name, identityName(identity))
+	return nil
+}
+
+// tokenError reports that no valid OAuth token is available for a server,
+// e.g. because the refresh token has expired.
+type tokenError struct {
+	err error
+}
+
+func (e *tokenError) Error() string {
+	return fmt.Sprintf("OAuth failed: %v", e.err)
+}
+
+func (e *tokenError) Unwrap() error {
+	return e.err
+}
+
+// resolveHeaders returns the headers for a server, including an OAuth token if
+// applicable, which is refreshed through the server's transport if needed. No
+// token is needed when replaying a cassette.
+func resolveHeaders(server *config.Server, rt http.RoundTripper) (map[string]string, error) {
 	headers := server.ExpandHeaders()
-	if server.OAuth {
-		token, err := oauth.GetValidToken(server.URL)
+	if server.OAuth && replayer == nil {
+		token, err := oauth.GetValidToken(oauthClient(rt), server.URL, server.Identity, server.Issuer)
 		if err != nil {
-			return nil, fmt.Errorf("OAuth failed: %w\nRun 'mcpli update %s' to re-authenticate", err, serverName)
+			return nil, &tokenError{err: err}
 		}
 		headers["Authorization"] = "Bearer " + token
 	}
 	return headers, nil
 }
+
+// isAuthError returns true if err means the server's OAuth credentials are
+// missing, expired or were rejected.
+func isAuthError(server *config.Server, err error) bool {
+	var unauthorizedErr *mcp.UnauthorizedError
+	var tokenErr *tokenError
+	return server.OAuth && (errors.As(err, &unauthorizedErr) || errors.As(err, &tokenErr))
+}
+
+// reauthenticate recovers from missing or rejected OAuth credentials. A token
+// rejected with 401 (e.g. revoked) is refreshed first; if that isn't possible,
+// the authorization flow runs again on an interactive terminal. Returns true
+// if the call should be retried.
+func reauthenticate(serverName string, server *config.Server, rt http.RoundTripper, err error) (bool, error) {
+	if !isAuthError(server, err) {
+		return false, nil
+	}
+
+	var challenge string
+	var unauthorizedErr *mcp.UnauthorizedError
+	if errors.As(err, &unauthorizedErr) {
+		challenge = unauthorizedErr.WWWAuthenticate
+		if _, refreshErr := oauth.Refresh(oauthClient(rt), server.URL, server.Identity, server.Issuer); refreshErr == nil {
+			return true, nil
+		}
+	}
+
+	if !terminal.IsInteractive() {
+		return false, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Credentials for %q are no longer valid, re-authenticating...\n", serverName)
+	opts := authOptions(server)
+	opts.Challenge = challenge
+	entry, authErr := oauth.Authenticate(oauthClient(rt), server.URL, opts)
+	if authErr != nil {
+		return false, fmt.Errorf("re-authentication failed: %w", authErr)
+	}
+
+	if err := saveIssuer(serverName, server, entry.Issuer); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// authOptions returns the OAuth options configured for a server.
+func authOptions(server *config.Server) oauth.AuthOptions {
+	return oauth.AuthOptions{
+		Scopes:            server.Scopes,
+		ClientCredentials: server.GrantType == oauth.GrantClientCredentials,
+		ClientID:          server.ClientID,
+		ClientSecret:      server.ClientSecret,
+		RedirectHost:      server.RedirectHost,
+		RedirectPort:      server.RedirectPort,
+		RedirectPortRange: server.RedirectPortRange,
+		Identity:          server.Identity,
+	}
+}
+
+// splitScopes flattens scope flag values, which may each hold several
+// space-separated scopes.
+func splitScopes(values []string) []string {
+	var scopes []string
+	for _, v := range values {
+		scopes = append(scopes, strings.Fields(v)...)
+	}
+	return scopes
+}
+
+// stepUpAuthorization handles a 403 insufficient_scope response by offering to
+// re-authorize with the additional scopes the server asked for, on top of the
+// requested and granted ones. The scopes to request are persisted in the
+// server config. Returns false if step-up is not applicable or the user
+// declined.
+func stepUpAuthorization(serverName string, server *config.Server, rt http.RoundTripper, err error) (bool, error) {
+	var forbiddenErr *mcp.ForbiddenError
+	if !server.OAuth || !errors.As(err, &forbiddenErr) {
+		return false, nil
+	}
+
+	challenge := oauth.ParseChallenge(forbiddenErr.WWWAuthenticate)
+	if !challenge.InsufficientScope() || !terminal.IsInteractive() {
+		return false, nil
+	}
+
+	scopes := mergeScopes(mergeScopes(server.Scopes, grantedScopes(server)), challenge.Scopes())
+	question := fmt.Sprintf("Server %q requires additional permissions (%s). Re-authorize?", serverName, strings.Join(scopes, " "))
+	if !terminal.Confirm(question) {
+		return false, nil
+	}
+
+	opts := authOptions(server)
+	opts.Scopes = scopes
+	opts.Challenge = forbiddenErr.WWWAuthenticate
+	entry, authErr := oauth.Authenticate(oauthClient(rt), server.URL, opts)
+	if authErr != nil {
+		return false, fmt.Errorf("re-authorization failed: %w", authErr)
+	}
+
+	server.Scopes = scopes
+	if err := saveIssuer(serverName, server, entry.Issuer); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// grantedScopes returns the scopes granted to the server's current
+// credentials.
+func grantedScopes(server *config.Server) []string {
+	store, err := oauth.LoadStore()
+	if err != nil {
+		return nil
+	}
+	if entry := store.Entry(server.URL, server.Identity, server.Issuer); entry != nil {
+		return strings.Fields(entry.Scope)
+	}
+	return nil
+}
+
+// saveIssuer pins the issuer of new credentials in the server config, so
+// that later logins use that authorization server's credentials, and saves
+// the requested scopes along with it. The scopes actually granted are only
+// kept with the credentials, so a partial grant doesn't narrow later logins.
+func saveIssuer(serverName string, server *config.Server, issuer string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	server.Issuer = issuer
+	if saved, ok := cfg.Servers[serverName]; ok {
+		saved.Issuer = server.Issuer
+		saved.Scopes = server.Scopes
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeScopes returns the union of two scope lists, preserving order.
+func mergeScopes(a, b []string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, s := range append(append([]string{}, a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
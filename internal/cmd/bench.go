@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/bench"
+	"github.com/juanibiapina/mcpli/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchArgs        string
+	benchConcurrency int
+	benchRequests    int
+)
+
+// histogramBuckets is the number of rows in the latency histogram.
+const histogramBuckets = 10
+
+// histogramWidth is the length of the longest histogram bar.
+const histogramWidth = 40
+
+var benchCmd = &cobra.Command{
+	Use:   "bench <server> <tool>",
+	Short: "Benchmark a tool",
+	Long: `Call a tool repeatedly with concurrent sessions and report throughput,
+latency percentiles, a latency histogram and a breakdown of errors.
+
+Each concurrent worker opens its own session before the timer starts.
+Exits non-zero if any request fails.
+
+Examples:
+  mcpli bench knuspr search_products --args '{"query": "milk"}'
+  mcpli bench knuspr get_cart --concurrency 8 --requests 200`,
+	Args: cobra.ExactArgs(2),
+	RunE: runBench,
+}
+
+func init() {
+	benchCmd.Flags().StringVar(&benchArgs, "args", "", "JSON arguments for the tool")
+	benchCmd.Flags().IntVar(&benchConcurrency, "concurrency", 8, "Number of concurrent sessions")
+	benchCmd.Flags().IntVar(&benchRequests, "requests", 200, "Total number of tool calls")
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	name, toolName := args[0], args[1]
+	if benchRequests < 1 || benchConcurrency < 1 {
+		return fmt.Errorf("--requests and --concurrency must be at least 1")
+	}
+
+	var arguments json.RawMessage
+	if benchArgs != "" {
+		arguments = json.RawMessage(benchArgs)
+		var test interface{}
+		if err := json.Unmarshal(arguments, &test); err != nil {
+			return fmt.Errorf("invalid JSON arguments: %w", err)
+		}
+	}
+
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+	headers, err := resolveHeaders(server, rt)
+	if err != nil {
+		if isAuthError(server, err) {
+			cmd.SilenceUsage = true
+			return authRequiredError(name, err)
+		}
+		return err
+	}
+
+	var clients []*mcp.Client
+	newWorker := func() (bench.Worker, error) {
+		client := newClient(server.URL, headers, rt)
+		if _, err := client.Initialize(); err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+		return func() error {
+			result, err := client.CallTool(toolName, arguments)
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				return errNoResult
+			}
+			var envelope toolCallEnvelope
+			if err := json.Unmarshal(result, &envelope); err == nil && envelope.IsError {
+				return errToolError
+			}
+			return nil
+		}, nil
+	}
+
+	fmt.Printf("Benchmarking %s %s: %d requests, concurrency %d\n\n", name, toolName, benchRequests, benchConcurrency)
+	result, err := bench.Run(benchRequests, benchConcurrency, newWorker, errorKind)
+	for _, client := range clients {
+		client.Terminate()
+	}
+	if err != nil {
+		if isAuthError(server, err) {
+			cmd.SilenceUsage = true
+			return authRequiredError(name, err)
+		}
+		return fmt.Errorf("failed to open session: %w", err)
+	}
+
+	printBenchResult(result)
+
+	if result.Failed() > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d requests failed", result.Failed(), result.Requests())
+	}
+	return nil
+}
+
+var (
+	errNoResult  = errors.New("no result")
+	errToolError = errors.New("tool error")
+)
+
+// errorKind names the kind of a failed request for the error breakdown.
+func errorKind(err error) string {
+	var unauthorizedErr *mcp.UnauthorizedError
+	var forbiddenErr *mcp.ForbiddenError
+	var httpErr *mcp.HTTPError
+	var rpcErr *mcp.RPCError
+	switch {
+	case errors.Is(err, errNoResult):
+		return "no result"
+	case errors.Is(err, errToolError):
+		return "tool error (isError)"
+	case errors.As(err, &unauthorizedErr):
+		return "HTTP 401"
+	case errors.As(err, &forbiddenErr):
+		return "HTTP 403"
+	case errors.As(err, &httpErr):
+		return fmt.Sprintf("HTTP %d", httpErr.StatusCode)
+	case errors.As(err, &rpcErr):
+		return fmt.Sprintf("JSON-RPC %d", rpcErr.Code)
+	}
+	return err.Error()
+}
+
+func printBenchResult(result *bench.Result) {
+	fmt.Printf("Completed %d requests in %s (%.1f req/s)\n", result.Requests(), result.Elapsed.Round(time.Millisecond), result.Throughput())
+	fmt.Printf("Succeeded: %d, failed: %d\n", len(result.Latencies), result.Failed())
+
+	if stats := bench.Summarize(result.Latencies); stats.Count > 0 {
+		fmt.Println()
+		fmt.Println("Latency:")
+		fmt.Printf("  min %s  avg %s  p50 %s  p95 %s  p99 %s  max %s\n",
+			formatLatency(stats.Min), formatLatency(stats.Avg), formatLatency(stats.P50),
+			formatLatency(stats.P95), formatLatency(stats.P99), formatLatency(stats.Max))
+
+		buckets := bench.Histogram(result.Latencies, histogramBuckets)
+		most := 0
+		for _, b := range buckets {
+			most = max(most, b.Count)
+		}
+		fmt.Println()
+		fmt.Println("Histogram:")
+		for _, b := range buckets {
+			bar := strings.Repeat("#", b.Count*histogramWidth/most)
+			fmt.Printf("  %9s - %9s  %5d  %s\n", formatLatency(b.Lower), formatLatency(b.Upper), b.Count, bar)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		kinds := make([]string, 0, len(result.Errors))
+		for kind := range result.Errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Slice(kinds, func(i, j int) bool { return result.Errors[kinds[i]] > result.Errors[kinds[j]] })
+
+		fmt.Println()
+		fmt.Println("Errors:")
+		for _, kind := range kinds {
+			fmt.Printf("  %5d  %s\n", result.Errors[kind], kind)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/juanibiapina/mcpli/internal/mcp"
+)
+
+var (
+	recordFile string
+	replayFile string
+	replayMode string
+
+	// recorder records the session's JSON-RPC exchanges when --record is given.
+	recorder *mcp.Recorder
+	// replayer serves the session from a cassette when --replay is given.
+	replayer *mcp.Replayer
+)
+
+// configureCassette sets up recording or replay from the cassette flags.
+func configureCassette() error {
+	if recordFile != "" && replayFile != "" {
+		return fmt.Errorf("--record and --replay cannot be used together")
+	}
+
+	var err error
+	if recordFile != "" {
+		if recorder, err = mcp.NewRecorder(recordFile); err != nil {
+			return err
+		}
+	}
+	if replayFile != "" {
+		if replayer, err = mcp.NewReplayer(replayFile, replayMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCassette fails a strict replay that left recorded exchanges unused,
+// since the session diverged from the recording.
+func checkCassette() error {
+	if replayer == nil || replayMode != mcp.ReplayStrict {
+		return nil
+	}
+	if n := replayer.Remaining(); n > 0 {
+		return fmt.Errorf("replay: %d recorded exchange(s) were not replayed", n)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/juanibiapina/mcpli/internal/doctor"
+	"github.com/juanibiapina/mcpli/internal/transport"
+	"github.com/spf13/cobra"
+)
+
+var doctorJSON bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor <server>",
+	Short: "Diagnose connectivity, authentication and spec conformance of a server",
+	Long: `Run a staged checklist against a server: DNS resolution, TCP connection,
+TLS, OAuth discovery, token validity, initialize, the initialized
+notification, ping, tools/list pagination, schema validity of the cached
+tools and session handling.
+
+Each check passes, warns or fails with a hint on how to fix it. Checks that
+depend on a failed one are skipped. Exits non-zero if any check fails.
+
+Examples:
+  mcpli doctor knuspr
+  mcpli doctor knuspr --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
+
+	target := doctor.Target{
+		Name:      name,
+		Server:    server,
+		Headers:   server.ExpandHeaders(),
+		Transport: rt,
+		NewClient: newClient,
+	}
+	if parsed, err := url.Parse(server.URL); err == nil {
+		target.Proxy, _ = transport.ProxyURL(transportOptions(server), parsed)
+	}
+
+	report := doctor.Run(target)
+
+	if doctorJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printDoctorReport(report)
+	}
+
+	if report.Failed() {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return &exitError{code: ExitFailure, err: fmt.Errorf("%d check(s) failed", report.Summary[doctor.Fail])}
+	}
+	return nil
+}
+
+// printDoctorReport prints one line per check, with hints indented below.
+func printDoctorReport(report *doctor.Report) {
+	fmt.Printf("Diagnosing %s (%s)\n\n", report.Server, report.URL)
+
+	width := 0
+	for _, check := range report.Checks {
+		width = max(width, len(check.Name))
+	}
+
+	for _, check := range report.Checks {
+		fmt.Printf("%-4s  %-*s  %s\n", strings.ToUpper(string(check.Status)), width, check.Name, check.Message)
+		if check.Hint != "" {
+			fmt.Printf("      %-*s  hint: %s\n", width, "", check.Hint)
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d warnings, %d failed, %d skipped\n",
+		report.Summary[doctor.Pass], report.Summary[doctor.Warn], report.Summary[doctor.Fail], report.Summary[doctor.Skip])
+}
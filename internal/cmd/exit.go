@@ -0,0 +1,33 @@
+package cmd
+
+import "fmt"
+
+// Exit codes. Scripts and agents can detect ExitAuthRequired and run
+// 'mcpli auth login <server>' before retrying.
+const (
+	ExitFailure      = 1
+	ExitAuthRequired = 3
+)
+
+// exitError makes the command exit with a specific code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// authRequiredError makes the command exit with ExitAuthRequired, with a
+// hint to log in to the server again.
+func authRequiredError(serverName string, err error) error {
+	return &exitError{
+		code: ExitAuthRequired,
+		err:  fmt.Errorf("%w\nRun 'mcpli auth login %s' to re-authenticate", err, serverName),
+	}
+}
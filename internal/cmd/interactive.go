@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/prompt"
+	"github.com/juanibiapina/mcpli/internal/schema"
+	"github.com/juanibiapina/mcpli/internal/terminal"
+)
+
+// needsPrompt reports whether the arguments of a tool call should be asked
+// for on the terminal: when --interactive is given, or when required
+// arguments are missing and mcpli runs in a terminal.
+func needsPrompt(tool config.Tool, arguments json.RawMessage, interactive bool) (bool, error) {
+	if interactive {
+		if !terminal.IsInteractive() {
+			return false, fmt.Errorf("--interactive needs a terminal")
+		}
+		return true, nil
+	}
+	if !terminal.IsInteractive() {
+		return false, nil
+	}
+
+	values := map[string]interface{}{}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &values); err != nil {
+			return false, nil
+		}
+	}
+	return len(schema.MissingRequired(tool.InputSchema, values)) > 0, nil
+}
+
+// promptArguments asks for a tool's arguments on the terminal, starting from
+// the given ones, and returns them once confirmed. With all set, given
+// arguments are asked again with their values as defaults.
+func promptArguments(tool config.Tool, arguments json.RawMessage, all bool) (json.RawMessage, error) {
+	values := map[string]interface{}{}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &values); err != nil {
+			return nil, fmt.Errorf("arguments must be a JSON object to be completed interactively")
+		}
+	}
+
+	p := prompt.New(os.Stdin, os.Stderr)
+	values, err := p.Arguments(tool.InputSchema, values, all)
+	if errors.Is(err, prompt.ErrInputEnded) {
+		return nil, &exitError{code: ExitFailure, err: errors.New("cancelled")}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pretty, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", pretty)
+	ok, err := p.Confirm(fmt.Sprintf("Call %s with these arguments?", tool.Name))
+	if err != nil && !errors.Is(err, prompt.ErrInputEnded) {
+		return nil, err
+	}
+	if !ok || err != nil {
+		return nil, &exitError{code: ExitFailure, err: errors.New("cancelled")}
+	}
+	return json.Marshal(values)
+}
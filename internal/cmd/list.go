@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 
+	"github.com/itchyny/gojq"
 	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/output"
 	"github.com/juanibiapina/mcpli/internal/terminal"
 	"github.com/spf13/cobra"
 )
@@ -14,19 +18,53 @@ var listCmd = &cobra.Command{
 	Long: `List configured servers, or list tools for a specific server.
 
 Examples:
-  mcpli list           # List all servers
-  mcpli list knuspr    # List tools for knuspr server`,
+  mcpli list                          # List all servers
+  mcpli list knuspr                   # List tools for knuspr server
+  mcpli list knuspr -o table          # List tools as a table
+  mcpli list knuspr -q '.[].name'     # List only the tool names`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runList,
 }
 
+func init() {
+	addOutputFlags(listCmd, output.Text)
+}
+
+// listedServer is a server as written by list with --output or --query.
+type listedServer struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Tools int    `json:"tools"`
+}
+
 func runList(cmd *cobra.Command, args []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	format, q, err := outputOptions(cmd)
+	if err != nil {
+		return err
+	}
+	if format == output.Text && q == nil {
+		format = ""
+	}
 
 	if len(args) == 0 {
+		if format != "" {
+			names := make([]string, 0, len(cfg.Servers))
+			for name := range cfg.Servers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			servers := []listedServer{}
+			for _, name := range names {
+				server := cfg.Servers[name]
+				servers = append(servers, listedServer{Name: name, URL: server.URL, Tools: len(server.Tools)})
+			}
+			return writeListed(format, q, servers)
+		}
+
 		// List servers
 		if len(cfg.Servers) == 0 {
 			fmt.Println("No servers configured. Use 'mcpli add' to add one.")
@@ -46,6 +84,14 @@ func runList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server %q not found", name)
 	}
 
+	if format != "" {
+		tools := server.Tools
+		if tools == nil {
+			tools = []config.Tool{}
+		}
+		return writeListed(format, q, tools)
+	}
+
 	if len(server.Tools) == 0 {
 		fmt.Println("No tools available")
 		return nil
@@ -71,3 +117,17 @@ func runList(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Use \"mcpli %s <tool> --help\" for more information about a tool.\n", name)
 	return nil
 }
+
+// writeListed writes servers or tools as decoded JSON, so that queries and
+// formats see the same fields as in the JSON output.
+func writeListed(format string, q *gojq.Code, listed interface{}) error {
+	data, err := json.Marshal(listed)
+	if err != nil {
+		return err
+	}
+	v, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	return writeOutput(format, q, v)
+}
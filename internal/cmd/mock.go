@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mock"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockFrom             string
+	mockSpec             string
+	mockListen           string
+	mockPath             string
+	mockStdio            bool
+	mockSSE              bool
+	mockLatency          time.Duration
+	mockErrorRate        float64
+	mockRequireToken     string
+	mockUnauthorizedRate float64
+	mockSessionTTL       time.Duration
+	mockPageSize         int
+)
+
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Run a mock MCP server",
+	Long: `Run a local MCP server exposing the cached tools of a configured server
+(--from) or the tools of a spec file (--spec).
+
+Tool calls return the canned response from the spec's "responses" map, a
+structuredContent generated from the tool's output schema, or a text result
+echoing the arguments. Calls missing required arguments return an error result.
+
+A spec file has the same shape as a server entry in the config file:
+  {
+    "server_info": {"name": "shop", "version": "1.0"},
+    "tools": [{"name": "search", "inputSchema": {"type": "object"}}],
+    "responses": {"search": {"content": [{"type": "text", "text": "milk"}]}}
+  }
+
+Failures can be simulated for testing clients:
+  --latency 200ms            delay every response
+  --error-rate 0.1           answer 10% of tool calls with a JSON-RPC error
+  --require-token TOKEN      reject requests without this bearer token (401)
+  --unauthorized-rate 0.1    reject 10% of requests with 401
+  --session-ttl 30s          expire sessions 30s after initialize (404)
+
+Examples:
+  mcpli mock --from knuspr --listen 127.0.0.1:8808
+  mcpli add knuspr-mock http://127.0.0.1:8808/mcp
+  mcpli mock --spec shop.json --stdio`,
+	Args: cobra.NoArgs,
+	RunE: runMock,
+}
+
+func init() {
+	mockCmd.Flags().StringVar(&mockFrom, "from", "", "Mock the cached tools of a configured server")
+	mockCmd.Flags().StringVar(&mockSpec, "spec", "", "Mock the server described by a spec file")
+	mockCmd.Flags().StringVar(&mockListen, "listen", "127.0.0.1:8808", "Address to listen on for streamable HTTP")
+	mockCmd.Flags().StringVar(&mockPath, "path", "/mcp", "URL path of the MCP endpoint")
+	mockCmd.Flags().BoolVar(&mockStdio, "stdio", false, "Serve on stdin/stdout instead of HTTP")
+	mockCmd.Flags().BoolVar(&mockSSE, "sse", false, "Answer HTTP requests with server-sent events instead of JSON")
+	mockCmd.Flags().DurationVar(&mockLatency, "latency", 0, "Delay every response (e.g. 200ms)")
+	mockCmd.Flags().Float64Var(&mockErrorRate, "error-rate", 0, "Fraction of tool calls answered with a JSON-RPC error (0-1)")
+	mockCmd.Flags().StringVar(&mockRequireToken, "require-token", "", "Reject HTTP requests without this bearer token with 401")
+	mockCmd.Flags().Float64Var(&mockUnauthorizedRate, "unauthorized-rate", 0, "Fraction of HTTP requests rejected with 401 (0-1)")
+	mockCmd.Flags().DurationVar(&mockSessionTTL, "session-ttl", 0, "Expire HTTP sessions this long after initialize (e.g. 30s)")
+	mockCmd.Flags().IntVar(&mockPageSize, "page-size", 0, "Paginate tools/list with this many tools per page")
+}
+
+func runMock(cmd *cobra.Command, args []string) error {
+	if (mockFrom == "") == (mockSpec == "") {
+		return fmt.Errorf("exactly one of --from or --spec is required")
+	}
+
+	spec, err := loadMockSpec()
+	if err != nil {
+		return err
+	}
+
+	opts := mock.Options{
+		Latency:          mockLatency,
+		ErrorRate:        mockErrorRate,
+		RequireToken:     config.ExpandEnv(mockRequireToken),
+		UnauthorizedRate: mockUnauthorizedRate,
+		SessionTTL:       mockSessionTTL,
+		SSE:              mockSSE,
+		PageSize:         mockPageSize,
+	}
+	for _, rate := range []float64{mockErrorRate, mockUnauthorizedRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("rates must be between 0 and 1, got %v", rate)
+		}
+	}
+
+	server := mock.New(spec, opts)
+	server.SetLog(os.Stderr)
+
+	if mockStdio {
+		return server.ServeStdio(os.Stdin, os.Stdout)
+	}
+
+	listener, err := net.Listen("tcp", mockListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	path := "/" + strings.TrimPrefix(mockPath, "/")
+	mux := http.NewServeMux()
+	mux.Handle(path, server)
+
+	fmt.Fprintf(os.Stderr, "Mock server %q with %d tools listening on http://%s%s\n", spec.ServerInfo.Name, len(spec.Tools), listener.Addr(), path)
+	return http.Serve(listener, mux)
+}
+
+// loadMockSpec returns the spec for --from or --spec.
+func loadMockSpec() (*mock.Spec, error) {
+	if mockSpec != "" {
+		return mock.LoadSpec(mockSpec)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	server, exists := cfg.Servers[mockFrom]
+	if !exists {
+		return nil, fmt.Errorf("server %q not found", mockFrom)
+	}
+
+	info := server.ServerInfo
+	if info.Name == "" {
+		info.Name = mockFrom
+	}
+	return &mock.Spec{ServerInfo: info, Tools: server.Tools}, nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/itchyny/gojq"
+	"github.com/juanibiapina/mcpli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlags adds --output and --query to a command.
+func addOutputFlags(cmd *cobra.Command, defaultFormat string) {
+	cmd.Flags().StringP("output", "o", defaultFormat, "Output format: "+strings.Join(output.Formats, ", "))
+	cmd.Flags().StringP("query", "q", "", "Filter the output with a jq expression, e.g. '.content[0].text'")
+}
+
+// outputOptions returns the format and compiled query of a command. The
+// format is empty if neither --output nor --query is given.
+func outputOptions(cmd *cobra.Command) (string, *gojq.Code, error) {
+	format, _ := cmd.Flags().GetString("output")
+	expr, _ := cmd.Flags().GetString("query")
+	if !cmd.Flags().Changed("output") {
+		format = ""
+		if expr != "" {
+			format = output.Raw
+		}
+	}
+	if format != "" {
+		if err := output.Check(format); err != nil {
+			return "", nil, err
+		}
+	}
+	if expr == "" {
+		return format, nil, nil
+	}
+	parsed, err := gojq.Parse(expr)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query: %w", err)
+	}
+	q, err := gojq.Compile(parsed)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return format, q, nil
+}
+
+// decodeJSON decodes JSON for writeOutput, keeping the text of numbers.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// writeOutput filters a value decoded with decodeJSON with a query, if there
+// is one, and writes the results in a format.
+func writeOutput(format string, q *gojq.Code, v interface{}) error {
+	values := []interface{}{v}
+	if q != nil {
+		values = nil
+		iter := q.Run(v)
+		for {
+			result, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if err, ok := result.(error); ok {
+				return fmt.Errorf("query failed: %w", err)
+			}
+			values = append(values, result)
+		}
+	}
+	return output.Write(os.Stdout, format, values)
+}
+
+// printResult prints the result of a tool call. Without --output and
+// --query it prints the result as the server sent it. With --structured
+// the structuredContent of the result is printed instead, and with
+// --output text and no query, the text of the content items.
+func printResult(cmd *cobra.Command, result json.RawMessage, format string, q *gojq.Code) error {
+	structured, _ := cmd.Flags().GetBool("structured")
+	if format == "" && !structured {
+		fmt.Println(string(result))
+		return nil
+	}
+
+	v, err := decodeJSON(result)
+	if err != nil {
+		return fmt.Errorf("invalid tool result: %w", err)
+	}
+	obj, _ := v.(map[string]interface{})
+	if structured {
+		content, ok := obj["structuredContent"]
+		if !ok {
+			return errors.New("tool result has no structuredContent")
+		}
+		v = content
+	} else if format == output.Text && q == nil {
+		v = contentText(obj)
+	}
+	if format == "" {
+		format = output.JSON
+	}
+	return writeOutput(format, q, v)
+}
+
+// contentText returns the text of the content items of a tool result, with
+// a placeholder for other items, such as "[image: image/png]".
+func contentText(result map[string]interface{}) []interface{} {
+	items, _ := result["content"].([]interface{})
+	texts := []interface{}{}
+	for _, item := range items {
+		obj, _ := item.(map[string]interface{})
+		typ, _ := obj["type"].(string)
+		if text, ok := obj["text"].(string); ok && typ == "text" {
+			texts = append(texts, text)
+			continue
+		}
+		description := typ
+		if mime, ok := obj["mimeType"].(string); ok {
+			description += ": " + mime
+		} else if resource, ok := obj["resource"].(map[string]interface{}); ok {
+			if uri, ok := resource["uri"].(string); ok {
+				description += ": " + uri
+			}
+		}
+		texts = append(texts, "["+description+"]")
+	}
+	return texts
+}
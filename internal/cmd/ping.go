@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/bench"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingCount    int
+	pingInterval time.Duration
+)
+
+var pingCmd = &cobra.Command{
+	Use:   "ping <server>",
+	Short: "Check that a server is up and measure its latency",
+	Long: `Open a session with a server and send MCP ping requests, then report
+min/avg/p95/max round-trip latency. Exits non-zero if no ping succeeds.
+
+Examples:
+  mcpli ping knuspr
+  mcpli ping knuspr --count 20 --interval 250ms`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPing,
+}
+
+func init() {
+	pingCmd.Flags().IntVarP(&pingCount, "count", "c", 4, "Number of pings to send")
+	pingCmd.Flags().DurationVarP(&pingInterval, "interval", "i", time.Second, "Time to wait between pings")
+}
+
+func runPing(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if pingCount < 1 {
+		return fmt.Errorf("--count must be at least 1")
+	}
+
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+
+	client, err := openSession(name, server)
+	if err != nil {
+		if isAuthError(server, err) {
+			cmd.SilenceUsage = true
+			return authRequiredError(name, err)
+		}
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Terminate()
+
+	fmt.Printf("PING %s (%s)\n", name, server.URL)
+
+	var latencies []time.Duration
+	for i := 1; i <= pingCount; i++ {
+		if i > 1 {
+			time.Sleep(pingInterval)
+		}
+
+		start := time.Now()
+		err := client.Ping()
+		latency := time.Since(start)
+		if err != nil {
+			fmt.Printf("ping %d: %v\n", i, err)
+			continue
+		}
+		latencies = append(latencies, latency)
+		fmt.Printf("ping %d: %s\n", i, formatLatency(latency))
+	}
+
+	noteToolsChanged(name, client)
+
+	stats := bench.Summarize(latencies)
+	fmt.Printf("\n--- %s ping statistics ---\n", name)
+	fmt.Printf("%d sent, %d ok, %d failed\n", pingCount, stats.Count, pingCount-stats.Count)
+	if stats.Count == 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("no ping succeeded")
+	}
+	fmt.Printf("min/avg/p95/max = %s/%s/%s/%s\n",
+		formatLatency(stats.Min), formatLatency(stats.Avg), formatLatency(stats.P95), formatLatency(stats.Max))
+	return nil
+}
+
+// formatLatency formats a latency in milliseconds with one decimal.
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mcp"
+	"github.com/juanibiapina/mcpli/internal/tooldiff"
+)
+
+// configTools converts the tools listed by a server to their cached form.
+func configTools(tools []mcp.Tool) []config.Tool {
+	converted := make([]config.Tool, len(tools))
+	for i, t := range tools {
+		converted[i] = config.Tool{
+			Name:         t.Name,
+			Description:  t.Description,
+			InputSchema:  t.InputSchema,
+			OutputSchema: t.OutputSchema,
+		}
+	}
+	return converted
+}
+
+// applyTools stores the server info and tools fetched from a server in its
+// config. The server info is kept when initResult is nil.
+func applyTools(server *config.Server, initResult *mcp.InitializeResult, tools []config.Tool) {
+	if initResult != nil {
+		server.ProtocolVersion = initResult.ProtocolVersion
+		server.ServerInfo = config.ServerInfo{
+			Name:    initResult.ServerInfo.Name,
+			Version: initResult.ServerInfo.Version,
+		}
+	}
+	server.Tools = tools
+	server.ToolsStale = false
+	server.UpdatedAt = time.Now()
+}
+
+// fetchTools opens a session with the client, lists the server's tools and
+// ends the session.
+func fetchTools(client *mcp.Client) (*mcp.InitializeResult, []config.Tool, error) {
+	initResult, err := client.Initialize()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize: %w", err)
+	}
+	defer client.Terminate()
+
+	toolsResult, err := client.ListTools()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	return initResult, configTools(toolsResult.Tools), nil
+}
+
+// parseRefreshAfter validates a --refresh-after value. "0" turns automatic
+// refreshing off.
+func parseRefreshAfter(value string) (string, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return "", fmt.Errorf("invalid --refresh-after %q: use a duration like 24h or 30m, or 0 to disable", value)
+	}
+	if d == 0 {
+		return "", nil
+	}
+	return value, nil
+}
+
+// summarizeDiff describes a tool diff in one line, e.g.
+// "1 added, 2 removed, 1 changed (breaking)".
+func summarizeDiff(diff *tooldiff.Diff) string {
+	if diff.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	if n := len(diff.Added); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", n))
+	}
+	if n := len(diff.Removed); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", n))
+	}
+	if n := len(diff.Changed); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", n))
+	}
+	summary := strings.Join(parts, ", ")
+	if diff.Breaking() {
+		summary += " (breaking)"
+	}
+	return summary
+}
+
+// backgroundRefresh fetches a server's tools in a separate session while a
+// command runs.
+type backgroundRefresh struct {
+	name       string
+	done       chan struct{}
+	initResult *mcp.InitializeResult
+	tools      []config.Tool
+	err        error
+}
+
+// pendingRefresh is the background refresh started by the current command.
+var pendingRefresh *backgroundRefresh
+
+// startBackgroundRefresh refreshes the cached tools of a stale server, in a
+// new session next to the client's.
+// Nothing is refreshed while recording or replaying, so cassettes only hold
+// the command's own exchanges.
+func startBackgroundRefresh(name string, server *config.Server, client *mcp.Client) {
+	if pendingRefresh != nil || recorder != nil || replayer != nil || !server.Stale() {
+		return
+	}
+
+	refresh := &backgroundRefresh{name: name, done: make(chan struct{})}
+	go func() {
+		defer close(refresh.done)
+		refresh.initResult, refresh.tools, refresh.err = fetchTools(client.Clone())
+	}()
+	pendingRefresh = refresh
+}
+
+// changedSession is a session whose server announced a change to its tools.
+type changedSession struct {
+	name   string
+	client *mcp.Client
+}
+
+// changedTools is the session of the current command whose server sent
+// notifications/tools/list_changed.
+var changedTools *changedSession
+
+// noteToolsChanged remembers the client's session if its server announced a
+// change to its tools, so the tools are fetched again after the command.
+// As with background refreshes, nothing is fetched while recording or
+// replaying.
+func noteToolsChanged(name string, client *mcp.Client) {
+	if recorder != nil || replayer != nil {
+		return
+	}
+	if client.ToolsChanged() {
+		changedTools = &changedSession{name: name, client: client}
+	}
+}
+
+// finishRefresh runs after every command: it saves the tools of a background
+// refresh and fetches the tools of a server that announced a change.
+func finishRefresh() {
+	finishBackgroundRefresh()
+	refreshChangedTools()
+}
+
+// finishBackgroundRefresh waits for the pending background refresh, if any,
+// and saves the fetched tools.
+func finishBackgroundRefresh() {
+	refresh := pendingRefresh
+	if refresh == nil {
+		return
+	}
+	pendingRefresh = nil
+	<-refresh.done
+
+	if refresh.err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to refresh the cached tools of %q: %v\n", refresh.name, refresh.err)
+		return
+	}
+	saveRefreshedTools(refresh.name, refresh.initResult, refresh.tools,
+		fmt.Sprintf("Refreshed the cached tools of %q", refresh.name))
+}
+
+// refreshChangedTools fetches the tools of a server that announced a change
+// and saves them. The command's session is left open, and the server may have
+// ended it by now, so the tools are listed in a new session of a clone of its
+// client.
+func refreshChangedTools() {
+	changed := changedTools
+	if changed == nil {
+		return
+	}
+	changedTools = nil
+
+	initResult, tools, err := fetchTools(changed.client.Clone())
+	if err != nil {
+		// Mark the cache stale so the next invocation refreshes it
+		fmt.Fprintf(os.Stderr, "Warning: server %q changed its tools, but they could not be fetched: %v\n", changed.name, err)
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
+			return
+		}
+		if server, exists := cfg.Servers[changed.name]; exists {
+			server.ToolsStale = true
+			if err := cfg.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save config: %v\n", err)
+			}
+		}
+		return
+	}
+	saveRefreshedTools(changed.name, initResult, tools,
+		fmt.Sprintf("Server %q changed its tools", changed.name))
+}
+
+// saveRefreshedTools stores the tools of a server in the config and reports
+// changes on stderr below the given heading.
+func saveRefreshedTools(name string, initResult *mcp.InitializeResult, tools []config.Tool, heading string) {
+	// Reload the config, which the command may have changed meanwhile
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
+		return
+	}
+	server, exists := cfg.Servers[name]
+	if !exists {
+		return
+	}
+
+	diff := tooldiff.Compare(server.Tools, tools)
+	applyTools(server, initResult, tools)
+	if err := cfg.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save config: %v\n", err)
+		return
+	}
+
+	if !diff.Empty() {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", heading, summarizeDiff(diff))
+		printToolDiff(os.Stderr, diff)
+	}
+}
@@ -34,11 +34,11 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server %q not found", name)
 	}
 
-	// Clean up OAuth credentials if applicable
-	if server.OAuth {
+	// Clean up OAuth credentials if applicable, unless another server uses them
+	if server.OAuth && !credentialsShared(cfg, name, server) {
 		store, err := oauth.LoadStore()
 		if err == nil {
-			store.Delete(server.URL)
+			store.Delete(server.URL, server.Identity, server.Issuer)
 			_ = store.Save()
 		}
 	}
@@ -53,3 +53,19 @@ func runRemove(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Server %q removed\n", name)
 	return nil
 }
+
+// credentialsShared returns true if another configured server may use the
+// same OAuth credentials as the named server. Servers that have not pinned
+// an issuer may use the credentials of any issuer.
+func credentialsShared(cfg *config.Config, name string, server *config.Server) bool {
+	key := oauth.EntryKey(server.URL, server.Identity, "")
+	for otherName, other := range cfg.Servers {
+		if otherName == name || oauth.EntryKey(other.URL, other.Identity, "") != key {
+			continue
+		}
+		if server.Issuer == "" || other.Issuer == "" || server.Issuer == other.Issuer {
+			return true
+		}
+	}
+	return false
+}
@@ -3,12 +3,17 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/juanibiapina/mcpli/internal/config"
 	"github.com/juanibiapina/mcpli/internal/mcp"
+	"github.com/juanibiapina/mcpli/internal/output"
 	"github.com/juanibiapina/mcpli/internal/terminal"
+	"github.com/juanibiapina/mcpli/internal/toolargs"
 	"github.com/juanibiapina/mcpli/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -29,12 +34,31 @@ Examples:
   mcpli add knuspr https://mcp.knuspr.de/mcp/ --header "rhl-email: \${ROHLIK_USERNAME}"
   mcpli knuspr search_products '{"query": "milk"}'
   mcpli knuspr get_cart`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := configureTrace(); err != nil {
+			return err
+		}
+		if err := configureCassette(); err != nil {
+			return err
+		}
+		return configureAuthStore()
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		return checkCassette()
+	},
 }
 
 // Execute runs the root command
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	err := rootCmd.Execute()
+	finishRefresh()
+	closeTrace()
+	if err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
+		os.Exit(ExitFailure)
 	}
 }
 
@@ -42,11 +66,24 @@ func init() {
 	// Set version for --version flag
 	rootCmd.Version = version.Version
 
+	rootCmd.PersistentFlags().BoolVarP(&traceEnabled, "trace", "v", false, "Trace HTTP traffic (requests, responses, JSON-RPC bodies, SSE events, timings) to stderr")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", "", "Write the trace to a file instead of stderr (implies --trace)")
+	rootCmd.PersistentFlags().BoolVar(&traceUnredacted, "trace-unredacted", false, "Do not redact credentials in the trace (local debugging only)")
+	rootCmd.PersistentFlags().StringVar(&recordFile, "record", "", "Record every JSON-RPC exchange of the session into a cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayFile, "replay", "", "Serve responses from a cassette file instead of the network")
+	rootCmd.PersistentFlags().StringVar(&replayMode, "replay-mode", mcp.ReplayStrict, "How --replay matches requests: strict (recorded order and params) or lenient (any recorded request with the same method and params)")
+
 	// Add built-in commands
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(removeCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(mockCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(pingCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(shellCmd)
 
 	// Load config and add server commands dynamically
 	cfg, err := config.Load()
@@ -109,56 +146,84 @@ func printServerHelp(name string, server *config.Server) {
 
 // createToolCommand creates a command for a specific tool
 func createToolCommand(serverName string, server *config.Server, tool config.Tool) *cobra.Command {
+	var fields []*fieldFlag
+	var conflicts []string
 	cmd := &cobra.Command{
-		Use:   tool.Name + " [json-arguments]",
+		Use:   tool.Name + " [json-arguments | - | @file]",
 		Short: truncateDescription(tool.Description, 60),
 		Long:  tool.Description,
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Parse arguments
-			var arguments json.RawMessage
-			if len(args) > 0 {
-				arguments = json.RawMessage(args[0])
-				// Validate it's valid JSON
-				var test interface{}
-				if err := json.Unmarshal(arguments, &test); err != nil {
-					return failWithToolHelp(cmd, fmt.Errorf("invalid JSON arguments: %w", err))
-				}
+			arguments, err := readArguments(cmd, args, tool, fields)
+			if err != nil {
+				return failWithToolHelp(cmd, err)
 			}
-
-			// Resolve headers (including OAuth token if applicable)
-			headers, err := resolveHeaders(serverName, server)
+			if err := checkFlagConflicts(cmd, conflicts, arguments); err != nil {
+				return failWithToolHelp(cmd, err)
+			}
+			format, q, err := outputOptions(cmd)
 			if err != nil {
 				return failWithToolHelp(cmd, err)
 			}
 
-			// Create client
-			client := mcp.NewClient(server.URL, headers)
-
-			// Run the initialization handshake so servers that enforce the
-			// MCP lifecycle (and any session id they issue) are honored
-			// before calling the tool.
-			if _, err := client.Initialize(); err != nil {
-				return failWithToolHelp(cmd, err)
+			// Ask for missing arguments on a terminal
+			interactive, _ := cmd.Flags().GetBool("interactive")
+			ask, err := needsPrompt(tool, arguments, interactive)
+			if err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			if ask {
+				cmd.SilenceUsage = true
+				if arguments, err = promptArguments(tool, arguments, interactive); err != nil {
+					return err
+				}
 			}
 
-			// Call the tool
-			result, err := client.CallTool(tool.Name, arguments)
+			rt, err := useServerTransport(serverName, server)
 			if err != nil {
 				return failWithToolHelp(cmd, err)
 			}
+			result, err := invokeTool(serverName, server, rt, tool.Name, arguments)
+			if err != nil {
+				// The token may have been revoked or lack a scope this tool
+				// needs: re-authenticate and retry once.
+				retry, authErr := reauthenticate(serverName, server, rt, err)
+				if authErr == nil && !retry {
+					retry, authErr = stepUpAuthorization(serverName, server, rt, err)
+				}
+				if authErr != nil {
+					return failWithToolHelp(cmd, authErr)
+				}
+				if retry {
+					result, err = invokeTool(serverName, server, rt, tool.Name, arguments)
+				}
+				if err != nil {
+					if isAuthError(server, err) {
+						cmd.SilenceUsage = true
+						return authRequiredError(serverName, err)
+					}
+					return failWithToolHelp(cmd, err)
+				}
+			}
 
 			var envelope toolCallEnvelope
 			if err := json.Unmarshal(result, &envelope); err == nil && envelope.IsError {
 				return failWithToolHelp(cmd, fmt.Errorf("tool returned error response: %s", string(result)))
 			}
 
-			// Output raw JSON
-			fmt.Println(string(result))
-			return nil
+			cmd.SilenceUsage = true
+			return printResult(cmd, result, format, q)
 		},
 	}
 
+	cmd.Flags().BoolP("interactive", "i", false, "Ask for each argument, with the given ones as defaults, and confirm before calling")
+	cmd.Flags().String("arg-file", "", "Read the arguments from a file (- for stdin)")
+	cmd.Flags().String("input-format", toolargs.FormatAuto, "Format of the arguments: "+strings.Join(toolargs.Formats, ", "))
+	addOutputFlags(cmd, output.Raw)
+	cmd.Flags().Bool("structured", false, "Print the structuredContent of the result instead of the whole result")
+	fields, conflicts = addFieldFlags(cmd, tool)
+
 	// Set explicit help function to avoid inheriting parent's custom help
 	cmd.SetHelpFunc(func(c *cobra.Command, args []string) {
 		// Print Long description with word wrapping, then usage
@@ -170,12 +235,62 @@ func createToolCommand(serverName string, server *config.Server, tool config.Too
 		}
 
 		printToolInputSchema(tool)
+		if len(conflicts) > 0 {
+			fmt.Printf("Properties without a flag, because mcpli uses the name: %s\n", strings.Join(conflicts, ", "))
+			fmt.Println("Give them in the arguments.")
+			fmt.Println()
+		}
 		fmt.Print(c.UsageString())
 	})
 
 	return cmd
 }
 
+// invokeTool opens a session with the server and calls a tool.
+func invokeTool(serverName string, server *config.Server, rt http.RoundTripper, toolName string, arguments json.RawMessage) (json.RawMessage, error) {
+	client, err := connect(server, rt)
+	if err != nil {
+		return nil, err
+	}
+	startBackgroundRefresh(serverName, server, client)
+
+	// Call the tool
+	result, err := client.CallTool(toolName, arguments)
+	noteToolsChanged(serverName, client)
+	return result, err
+}
+
+// openSession creates a client for the server and runs the initialization
+// handshake.
+func openSession(serverName string, server *config.Server) (*mcp.Client, error) {
+	rt, err := useServerTransport(serverName, server)
+	if err != nil {
+		return nil, err
+	}
+	return connect(server, rt)
+}
+
+// connect creates a client for the server that uses the given transport and
+// runs the initialization handshake.
+func connect(server *config.Server, rt http.RoundTripper) (*mcp.Client, error) {
+	// Resolve headers (including OAuth token if applicable)
+	headers, err := resolveHeaders(server, rt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create client
+	client := newClient(server.URL, headers, rt)
+
+	// Run the initialization handshake so servers that enforce the
+	// MCP lifecycle (and any session id they issue) are honored
+	// before calling the tool.
+	if _, err := client.Initialize(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 func failWithToolHelp(cmd *cobra.Command, err error) error {
 	cmd.SilenceErrors = true
 	cmd.SilenceUsage = true
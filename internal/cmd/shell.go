@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mcp"
+	"github.com/juanibiapina/mcpli/internal/shell"
+	"github.com/juanibiapina/mcpli/internal/terminal"
+	"github.com/juanibiapina/mcpli/internal/toolargs"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// shellHistorySize is the number of lines kept in a server's shell history.
+const shellHistorySize = 1000
+
+// shellCommands are the built-in commands of the shell.
+var shellCommands = []string{":help", ":tools", ":resources", ":prompts", ":vars", ":quit"}
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <server>",
+	Short: "Open an interactive shell for a server",
+	Long: `Open one session with a server and call its tools interactively:
+
+  <tool> [arguments]        Call a tool, with JSON, JSON5 or YAML arguments
+  :help [tool]              Show the commands, or a tool's description and schema
+  :tools                    List the tools
+  :resources                List the server's resources
+  :prompts                  List the server's prompts
+  :vars                     List the saved results
+  :quit                     Leave the shell (or Ctrl-D)
+
+Tab completes commands, tool names and the argument names of a tool. Each
+result is saved as $1, $2, ... and the latest as $_; use them in later
+arguments, selecting fields with .field and [index]:
+
+  search_products {"query": "milk"}
+  add_to_cart {"product_id": $1.structuredContent.products[0].id}
+
+History is kept per server in the mcpli state directory.
+
+Example:
+  mcpli shell knuspr`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShell,
+}
+
+// shellSession is the state of a running shell.
+type shellSession struct {
+	name      string
+	server    *config.Server
+	client    *mcp.Client
+	vars      shell.Vars
+	completer *shell.Completer
+}
+
+func runShell(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	server, err := loadServer(name)
+	if err != nil {
+		return err
+	}
+
+	client, err := openSession(name, server)
+	if err != nil {
+		if isAuthError(server, err) {
+			cmd.SilenceUsage = true
+			return authRequiredError(name, err)
+		}
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+
+	s := &shellSession{
+		name:      name,
+		server:    server,
+		client:    client,
+		completer: shell.NewCompleter(server.Tools, shellCommands),
+	}
+	defer func() {
+		noteToolsChanged(name, s.client)
+		s.client.Terminate()
+	}()
+
+	readLine, closeInput, err := s.input()
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == ":quit" || line == ":exit" {
+			return nil
+		}
+		s.run(line)
+	}
+}
+
+// input returns a function reading the next line. On a terminal, lines are
+// read with line editing, completion and persistent history; otherwise they
+// are read plainly from stdin, so commands can be piped in.
+func (s *shellSession) input() (func() (string, error), func(), error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		return func() (string, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}, func() {}, nil
+	}
+
+	history, err := shell.LoadHistory(filepath.Join(xdg.StateHome, "mcpli", "history", s.name), shellHistorySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load shell history: %w", err)
+	}
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, s.name+"> ")
+	t.History = history
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		return s.completer.Complete(line, pos, key)
+	}
+
+	fmt.Printf("Connected to %s. Type :help for commands, Ctrl-D to quit.\n", s.name)
+
+	// The terminal is raw only while a line is read, so command output is
+	// printed normally
+	return func() (string, error) {
+		if width, height, err := term.GetSize(fd); err == nil && width > 0 {
+			t.SetSize(width, height)
+		}
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(fd, state)
+
+		line, err := t.ReadLine()
+		if err == term.ErrPasteIndicator {
+			err = nil
+		}
+		return line, err
+	}, func() { fmt.Println() }, nil
+}
+
+// run runs one shell line.
+func (s *shellSession) run(line string) {
+	command, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	var err error
+	switch command {
+	case ":help":
+		err = s.help(rest)
+	case ":tools":
+		s.listTools()
+	case ":resources":
+		err = s.listResources()
+	case ":prompts":
+		err = s.listPrompts()
+	case ":vars":
+		s.listVars()
+	default:
+		if strings.HasPrefix(command, ":") {
+			err = fmt.Errorf("unknown command %q (type :help for commands)", command)
+		} else {
+			err = s.callTool(command, rest)
+		}
+	}
+
+	if err != nil {
+		if isAuthError(s.server, err) {
+			err = fmt.Errorf("%w\nRun 'mcpli auth login %s' to re-authenticate", err, s.name)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// callTool calls a tool with arguments in which saved results are expanded,
+// prints the result and saves it.
+func (s *shellSession) callTool(name, args string) error {
+	var arguments json.RawMessage
+	if args != "" {
+		expanded, err := s.vars.Expand(args)
+		if err != nil {
+			return err
+		}
+		tool, _ := s.findTool(name)
+		if arguments, err = toolargs.Parse([]byte(expanded), toolargs.FormatAuto, tool.InputSchema); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.client.CallTool(name, arguments)
+	if expiredSession(err) {
+		fmt.Fprintln(os.Stderr, "Session expired, reconnecting...")
+		if err = s.reconnect(); err == nil {
+			result, err = s.client.CallTool(name, arguments)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return fmt.Errorf("the server returned no result")
+	}
+	s.refreshToolsIfChanged()
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, result, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(result)
+	}
+	fmt.Println(pretty.String())
+
+	n, err := s.vars.Add(result)
+	if err != nil {
+		return err
+	}
+	var envelope toolCallEnvelope
+	if err := json.Unmarshal(result, &envelope); err == nil && envelope.IsError {
+		fmt.Printf("(saved as $%d; the tool returned an error)\n", n)
+	} else {
+		fmt.Printf("(saved as $%d)\n", n)
+	}
+	return nil
+}
+
+// expiredSession reports whether err means the server no longer knows the
+// session.
+func expiredSession(err error) bool {
+	var httpErr *mcp.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// reconnect opens a new session in place of an expired one.
+func (s *shellSession) reconnect() error {
+	client, err := openSession(s.name, s.server)
+	if err != nil {
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+// refreshToolsIfChanged fetches the tools again when the server announced a
+// change, so completion and :help know about them.
+func (s *shellSession) refreshToolsIfChanged() {
+	if !s.client.ToolsChanged() {
+		return
+	}
+	result, err := s.client.ListTools()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: server %q changed its tools, but they could not be fetched: %v\n", s.name, err)
+		return
+	}
+	s.server.Tools = configTools(result.Tools)
+	s.completer = shell.NewCompleter(s.server.Tools, shellCommands)
+	saveRefreshedTools(s.name, nil, s.server.Tools, fmt.Sprintf("Server %q changed its tools", s.name))
+}
+
+// findTool returns the cached tool with the given name.
+func (s *shellSession) findTool(name string) (config.Tool, bool) {
+	for _, tool := range s.server.Tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return config.Tool{}, false
+}
+
+func (s *shellSession) help(toolName string) error {
+	if toolName == "" {
+		fmt.Println(`Commands:
+  <tool> [arguments]        Call a tool; results are saved as $1, $2, ... and $_
+  :help [tool]              Show this help, or a tool's description and schema
+  :tools                    List the tools
+  :resources                List the server's resources
+  :prompts                  List the server's prompts
+  :vars                     List the saved results
+  :quit                     Leave the shell (or Ctrl-D)`)
+		return nil
+	}
+
+	tool, ok := s.findTool(toolName)
+	if !ok {
+		return fmt.Errorf("unknown tool %q (type :tools to list them)", toolName)
+	}
+	fmt.Println(tool.Name)
+	if tool.Description != "" {
+		fmt.Println(terminal.WrapText(tool.Description, terminal.GetWidth(), ""))
+	}
+	fmt.Println()
+	printToolInputSchema(tool)
+	return nil
+}
+
+func (s *shellSession) listTools() {
+	for _, tool := range s.server.Tools {
+		fmt.Printf("  %-30s %s\n", tool.Name, truncateDescription(strings.Join(strings.Fields(tool.Description), " "), 60))
+	}
+}
+
+func (s *shellSession) listResources() error {
+	result, err := s.client.ListResources()
+	if err != nil {
+		return err
+	}
+	if len(result.Resources) == 0 {
+		fmt.Println("No resources")
+		return nil
+	}
+	for _, r := range result.Resources {
+		fmt.Printf("  %s\n", r.URI)
+		details := r.Name
+		if r.MimeType != "" {
+			details += " (" + r.MimeType + ")"
+		}
+		if r.Description != "" {
+			details += " - " + truncateDescription(r.Description, 60)
+		}
+		fmt.Printf("      %s\n", details)
+	}
+	return nil
+}
+
+func (s *shellSession) listPrompts() error {
+	result, err := s.client.ListPrompts()
+	if err != nil {
+		return err
+	}
+	if len(result.Prompts) == 0 {
+		fmt.Println("No prompts")
+		return nil
+	}
+	for _, p := range result.Prompts {
+		args := make([]string, len(p.Arguments))
+		for i, a := range p.Arguments {
+			args[i] = a.Name
+			if a.Required {
+				args[i] += "*"
+			}
+		}
+		fmt.Printf("  %s(%s)\n", p.Name, strings.Join(args, ", "))
+		if p.Description != "" {
+			fmt.Printf("      %s\n", truncateDescription(p.Description, 70))
+		}
+	}
+	return nil
+}
+
+func (s *shellSession) listVars() {
+	if s.vars.Len() == 0 {
+		fmt.Println("No saved results")
+		return
+	}
+	for n := 1; n <= s.vars.Len(); n++ {
+		value, _ := s.vars.Get(fmt.Sprint(n))
+		data, _ := json.Marshal(value)
+		fmt.Printf("  $%-4d %s\n", n, truncateDescription(string(data), 70))
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mcp"
+	"github.com/juanibiapina/mcpli/internal/transport"
+)
+
+var (
+	traceEnabled    bool
+	traceFile       string
+	traceUnredacted bool
+
+	// tracer traces HTTP traffic when --trace is given.
+	tracer *transport.Tracer
+	// traceOutput is the file given with --trace-file.
+	traceOutput *os.File
+)
+
+// configureTrace sets up the tracer from the trace flags.
+func configureTrace() error {
+	if !traceEnabled && traceFile == "" {
+		return nil
+	}
+
+	var w *os.File = os.Stderr
+	if traceFile != "" {
+		f, err := os.OpenFile(traceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open trace file: %w", err)
+		}
+		w = f
+		traceOutput = f
+	}
+
+	tracer = transport.NewTracer(w, transport.TraceOptions{Unredacted: traceUnredacted})
+	return nil
+}
+
+// closeTrace closes the trace file, if any.
+func closeTrace() {
+	if traceOutput != nil {
+		traceOutput.Close()
+	}
+}
+
+// envReference matches ${VAR} references in header values.
+var envReference = regexp.MustCompile(`\$\{[^}]+\}`)
+
+// secretHeaders returns the configured headers of a server whose values come
+// from environment variables, which is how secrets are configured. They are
+// redacted from traces along with the tracer's fixed list of credential
+// headers.
+func secretHeaders(server *config.Server) []string {
+	var names []string
+	for name, value := range server.Headers {
+		if envReference.MatchString(value) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// useServerTransport builds the HTTP transport for a server's TLS and proxy
+// settings, for MCP and OAuth requests alike. It warns loudly when certificate
+// verification is disabled.
+func useServerTransport(name string, server *config.Server) (http.RoundTripper, error) {
+	if server.InsecureSkipVerify {
+		fmt.Fprintf(os.Stderr, "WARNING: TLS certificate verification is disabled for server %q (insecure_skip_verify).\n", name)
+		fmt.Fprintln(os.Stderr, "WARNING: Anyone on the network path can intercept its traffic and credentials.")
+	}
+
+	rt, err := transport.New(transportOptions(server))
+	if err != nil {
+		return nil, fmt.Errorf("invalid transport settings for server %q: %w", name, err)
+	}
+
+	if tracer != nil {
+		rt = tracer.Wrap(rt, secretHeaders(server))
+	}
+
+	return rt, nil
+}
+
+// oauthClient returns the HTTP client for a server's OAuth requests, which go
+// through the same transport as its MCP requests.
+func oauthClient(rt http.RoundTripper) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+// transportOptions returns the transport options for a server's TLS and proxy
+// settings.
+func transportOptions(server *config.Server) transport.Options {
+	var host string
+	if parsed, err := url.Parse(server.URL); err == nil {
+		host = parsed.Host
+	}
+
+	return transport.Options{
+		Host:               host,
+		CAFile:             config.ExpandEnv(server.CAFile),
+		ClientCert:         config.ExpandEnv(server.ClientCert),
+		ClientKey:          config.ExpandEnv(server.ClientKey),
+		InsecureSkipVerify: server.InsecureSkipVerify,
+		MinTLSVersion:      server.MinTLSVersion,
+		PinnedSPKI:         server.PinnedSPKI,
+		Proxy:              config.ExpandEnv(server.Proxy),
+		NoProxy:            server.NoProxy,
+	}
+}
+
+// newClient creates an MCP client that uses the given transport, recording
+// or replaying its session when requested.
+func newClient(url string, headers map[string]string, rt http.RoundTripper) *mcp.Client {
+	client := mcp.NewClient(url, headers)
+	client.SetTransport(rt)
+	if recorder != nil {
+		client.SetRecorder(recorder)
+	}
+	if replayer != nil {
+		client.SetReplayer(replayer)
+	}
+	return client
+}
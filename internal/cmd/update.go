@@ -3,28 +3,75 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"time"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 
 	"github.com/juanibiapina/mcpli/internal/config"
 	"github.com/juanibiapina/mcpli/internal/mcp"
 	"github.com/juanibiapina/mcpli/internal/oauth"
+	"github.com/juanibiapina/mcpli/internal/tooldiff"
 	"github.com/spf13/cobra"
 )
 
 var updateCmd = &cobra.Command{
 	Use:   "update <name>",
 	Short: "Update a server's tool definitions",
-	Long: `Refresh the cached tool definitions for a configured server.
+	Long: `Refresh the cached tool definitions for a configured server, or for every
+server with --all.
 
 Use this when the server has added new tools or updated existing ones.
+The changes to the cached tools are printed, with breaking changes (removed
+tools or properties, new required properties, incompatible types) flagged.
 
-Example:
-  mcpli update knuspr`,
-	Args: cobra.ExactArgs(1),
+Examples:
+  mcpli update knuspr
+  mcpli update knuspr --dry-run
+  mcpli update knuspr --fail-on-breaking
+  mcpli update --all --concurrency 8
+
+With --refresh-after, tool invocations refresh the cached tools in the
+background once they are older than the given duration:
+  mcpli update knuspr --refresh-after 24h`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runUpdate,
 }
 
+var (
+	updateDryRun         bool
+	updateFailOnBreaking bool
+	updateAll            bool
+	updateConcurrency    int
+	updateRefreshAfter   string
+)
+
+func init() {
+	updateCmd.Flags().BoolVar(&updateDryRun, "dry-run", false, "Show the tool changes without saving them")
+	updateCmd.Flags().BoolVar(&updateFailOnBreaking, "fail-on-breaking", false, "Exit non-zero and keep the cached tools if there are breaking changes")
+	updateCmd.Flags().BoolVar(&updateAll, "all", false, "Update every configured server")
+	updateCmd.Flags().IntVar(&updateConcurrency, "concurrency", 4, "Number of servers to update at once with --all")
+	updateCmd.Flags().StringVar(&updateRefreshAfter, "refresh-after", "", "Refresh the cached tools in the background when they are older than this, e.g. 24h (0 disables)")
+}
+
 func runUpdate(cmd *cobra.Command, args []string) error {
+	refreshAfter, err := updateRefreshAfterFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	if updateAll {
+		if len(args) > 0 {
+			return fmt.Errorf("--all does not take a server name")
+		}
+		return runUpdateAll(cmd, refreshAfter)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires a server name or --all")
+	}
 	name := args[0]
 
 	// Load config
@@ -38,15 +85,23 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	if !exists {
 		return fmt.Errorf("server %q not found", name)
 	}
+	if refreshAfter != nil {
+		server.RefreshAfter = *refreshAfter
+	}
+
+	rt, err := useServerTransport(name, server)
+	if err != nil {
+		return err
+	}
 
 	// Resolve headers (including OAuth token if applicable)
-	headers, err := resolveHeaders(name, server)
+	headers, err := resolveHeaders(server, rt)
 	if err != nil && !server.OAuth {
 		return err
 	}
 
 	// Create client
-	client := mcp.NewClient(server.URL, headers)
+	client := newClient(server.URL, headers, rt)
 
 	// Initialize connection
 	fmt.Printf("Connecting to %s...\n", server.URL)
@@ -56,6 +111,10 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	if err != nil && server.OAuth {
 		var unauthorizedErr *mcp.UnauthorizedError
 		needsReauth := errors.As(err, &unauthorizedErr)
+		var challenge string
+		if needsReauth {
+			challenge = unauthorizedErr.WWWAuthenticate
+		}
 		if !needsReauth {
 			// Also re-auth if resolveHeaders failed (e.g. refresh token expired)
 			// and we never got a chance to try the server
@@ -65,16 +124,22 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		}
 
 		if needsReauth {
-			if authErr := oauth.Authenticate(server.URL); authErr != nil {
-				return fmt.Errorf("re-authentication failed: %w", authErr)
+			opts := authOptions(server)
+			opts.Challenge = challenge
+			entry, authErr := oauth.Authenticate(oauthClient(rt), server.URL, opts)
+			if authErr != nil {
+				cmd.SilenceUsage = true
+				return authRequiredError(name, fmt.Errorf("re-authentication failed: %w", authErr))
 			}
+			server.Issuer = entry.Issuer
 
 			// Retry with fresh token
-			headers, err = resolveHeaders(name, server)
+			headers, err = resolveHeaders(server, rt)
 			if err != nil {
-				return fmt.Errorf("failed to get token after re-authentication: %w", err)
+				cmd.SilenceUsage = true
+				return authRequiredError(name, fmt.Errorf("failed to get token after re-authentication: %w", err))
 			}
-			client = mcp.NewClient(server.URL, headers)
+			client = newClient(server.URL, headers, rt)
 
 			initResult, err = client.Initialize()
 		}
@@ -93,24 +158,22 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("Found %d tools\n", len(toolsResult.Tools))
 
-	// Convert tools to config format
-	tools := make([]config.Tool, len(toolsResult.Tools))
-	for i, t := range toolsResult.Tools {
-		tools[i] = config.Tool{
-			Name:        t.Name,
-			Description: t.Description,
-			InputSchema: t.InputSchema,
-		}
+	tools := configTools(toolsResult.Tools)
+
+	diff := tooldiff.Compare(server.Tools, tools)
+	printToolDiff(os.Stdout, diff)
+
+	if updateFailOnBreaking && diff.Breaking() {
+		cmd.SilenceUsage = true
+		return &exitError{code: ExitFailure, err: fmt.Errorf("server %q has breaking tool changes; cached tools not updated", name)}
+	}
+	if updateDryRun {
+		fmt.Println("Dry run: cached tools not updated")
+		return nil
 	}
 
 	// Update server config
-	server.ProtocolVersion = initResult.ProtocolVersion
-	server.ServerInfo = config.ServerInfo{
-		Name:    initResult.ServerInfo.Name,
-		Version: initResult.ServerInfo.Version,
-	}
-	server.Tools = tools
-	server.UpdatedAt = time.Now()
+	applyTools(server, initResult, tools)
 
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
@@ -119,3 +182,194 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Server %q updated successfully\n", name)
 	return nil
 }
+
+// updateRefreshAfterFlag returns the validated --refresh-after value, or nil
+// if the flag was not given.
+func updateRefreshAfterFlag(cmd *cobra.Command) (*string, error) {
+	if !cmd.Flags().Changed("refresh-after") {
+		return nil, nil
+	}
+	value, err := parseRefreshAfter(updateRefreshAfter)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// serverUpdate is the outcome of updating one server with --all.
+type serverUpdate struct {
+	name       string
+	client     *mcp.Client
+	initResult *mcp.InitializeResult
+	tools      []config.Tool
+	diff       *tooldiff.Diff
+	status     string
+	err        error
+}
+
+// runUpdateAll updates every configured server. Credentials are resolved one
+// server at a time, since token refreshes share the OAuth transport, then the
+// tools are fetched with bounded concurrency. Servers that need an
+// interactive login are reported instead of prompting.
+func runUpdateAll(cmd *cobra.Command, refreshAfter *string) error {
+	if updateConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.Servers) == 0 {
+		fmt.Println("No servers configured. Use 'mcpli add' to add one.")
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Servers))
+	for name := range cfg.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	updates := make([]*serverUpdate, len(names))
+	for i, name := range names {
+		server := cfg.Servers[name]
+		update := &serverUpdate{name: name}
+		updates[i] = update
+
+		rt, err := useServerTransport(name, server)
+		if err != nil {
+			update.err = err
+			continue
+		}
+		headers, err := resolveHeaders(server, rt)
+		if err != nil {
+			update.err = err
+			continue
+		}
+		update.client = newClient(server.URL, headers, rt)
+	}
+
+	fmt.Printf("Updating %d servers...\n", len(names))
+	sem := make(chan struct{}, updateConcurrency)
+	var wg sync.WaitGroup
+	for _, update := range updates {
+		if update.client == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(update *serverUpdate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			update.initResult, update.tools, update.err = fetchTools(update.client)
+		}(update)
+	}
+	wg.Wait()
+
+	changed, failed, breaking := false, false, false
+	for _, update := range updates {
+		server := cfg.Servers[update.name]
+		if update.err != nil {
+			failed = true
+			update.status = "failed"
+			if isAuthError(server, update.err) {
+				update.status = "auth required"
+			}
+			continue
+		}
+
+		update.diff = tooldiff.Compare(server.Tools, update.tools)
+		if !update.diff.Empty() {
+			fmt.Printf("\n%s\n", update.name)
+			printToolDiff(os.Stdout, update.diff)
+		}
+
+		switch {
+		case updateFailOnBreaking && update.diff.Breaking():
+			breaking = true
+			update.status = "breaking"
+		case updateDryRun:
+			update.status = "dry run"
+		default:
+			applyTools(server, update.initResult, update.tools)
+			if refreshAfter != nil {
+				server.RefreshAfter = *refreshAfter
+			}
+			changed = true
+			update.status = "updated"
+		}
+	}
+
+	if changed {
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	fmt.Println()
+	printUpdateSummary(updates)
+	for _, update := range updates {
+		if update.status == "auth required" {
+			fmt.Printf("Run 'mcpli auth login %s' to re-authenticate\n", update.name)
+		}
+	}
+
+	if failed || breaking {
+		cmd.SilenceUsage = true
+		return &exitError{code: ExitFailure, err: fmt.Errorf("not all servers were updated")}
+	}
+	return nil
+}
+
+// printUpdateSummary prints a table with one row per server.
+func printUpdateSummary(updates []*serverUpdate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVER\tSTATUS\tTOOLS\tCHANGES")
+	for _, update := range updates {
+		tools, changes := "-", ""
+		if update.err != nil {
+			changes = update.err.Error()
+			if i := strings.IndexByte(changes, '\n'); i >= 0 {
+				changes = changes[:i]
+			}
+		} else {
+			tools = strconv.Itoa(len(update.tools))
+			changes = summarizeDiff(update.diff)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", update.name, update.status, tools, changes)
+	}
+	w.Flush()
+}
+
+// printToolDiff prints the tool changes found by an update.
+func printToolDiff(w io.Writer, diff *tooldiff.Diff) {
+	if diff.Empty() {
+		fmt.Fprintln(w, "No tool changes")
+		return
+	}
+
+	fmt.Fprintln(w, "Tool changes:")
+	for _, name := range diff.Added {
+		fmt.Fprintf(w, "  + %s\n", name)
+	}
+	for _, name := range diff.Removed {
+		fmt.Fprintf(w, "  - %s  [breaking]\n", name)
+	}
+	for _, tc := range diff.Changed {
+		fmt.Fprintf(w, "  ~ %s\n", tc.Name)
+		if tc.DescriptionChanged {
+			fmt.Fprintln(w, "      description changed")
+		}
+		for _, c := range tc.Changes {
+			line := c.Message
+			if c.Path != "" {
+				line = c.Path + ": " + line
+			}
+			if c.Breaking {
+				line += "  [breaking]"
+			}
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+}
@@ -12,9 +12,10 @@ import (
 
 // Tool represents an MCP tool definition
 type Tool struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	InputSchema  json.RawMessage `json:"inputSchema,omitempty"`
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
 }
 
 // ServerInfo contains MCP server metadata
@@ -25,18 +26,42 @@ type ServerInfo struct {
 
 // Server represents a configured MCP server
 type Server struct {
-	URL             string            `json:"url"`
-	Headers         map[string]string `json:"headers,omitempty"`
-	OAuth           bool              `json:"oauth,omitempty"`
-	ProtocolVersion string            `json:"protocol_version"`
-	ServerInfo      ServerInfo        `json:"server_info"`
-	Tools           []Tool            `json:"tools"`
-	UpdatedAt       time.Time         `json:"updated_at"`
+	URL                string            `json:"url"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	OAuth              bool              `json:"oauth,omitempty"`
+	Scopes             []string          `json:"scopes,omitempty"`
+	GrantType          string            `json:"grant_type,omitempty"`
+	ClientID           string            `json:"client_id,omitempty"`
+	ClientSecret       string            `json:"client_secret,omitempty"`
+	RedirectHost       string            `json:"redirect_host,omitempty"`
+	RedirectPort       int               `json:"redirect_port,omitempty"`
+	RedirectPortRange  string            `json:"redirect_port_range,omitempty"`
+	Identity           string            `json:"identity,omitempty"`
+	Issuer             string            `json:"issuer,omitempty"`
+	CAFile             string            `json:"ca_file,omitempty"`
+	ClientCert         string            `json:"client_cert,omitempty"`
+	ClientKey          string            `json:"client_key,omitempty"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+	MinTLSVersion      string            `json:"min_tls_version,omitempty"`
+	PinnedSPKI         []string          `json:"pinned_spki,omitempty"`
+	Proxy              string            `json:"proxy,omitempty"`
+	NoProxy            string            `json:"no_proxy,omitempty"`
+	RefreshAfter       string            `json:"refresh_after,omitempty"`
+	ProtocolVersion    string            `json:"protocol_version"`
+	ServerInfo         ServerInfo        `json:"server_info"`
+	Tools              []Tool            `json:"tools"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	// ToolsStale is set when the server announced a change to its tools
+	// that has not been fetched yet.
+	ToolsStale bool `json:"tools_stale,omitempty"`
 }
 
 // Config represents the application configuration
 type Config struct {
-	Servers map[string]*Server `json:"servers"`
+	// AuthStore selects where OAuth credentials are stored: "file" (default),
+	// "keyring" or "age".
+	AuthStore string             `json:"auth_store,omitempty"`
+	Servers   map[string]*Server `json:"servers"`
 }
 
 // configPath returns the path to the config file
@@ -89,7 +114,12 @@ func (c *Config) Save() error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	// The config may hold client secrets and header values: keep it private,
+	// including when it was created with wider permissions.
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
 }
 
 // envVarRegex matches ${VAR_NAME} patterns
@@ -108,6 +138,26 @@ func ExpandEnv(s string) string {
 	})
 }
 
+// RefreshInterval returns how long the cached tools stay fresh, or 0 if
+// they are only refreshed by 'mcpli update'.
+func (s *Server) RefreshInterval() time.Duration {
+	d, err := time.ParseDuration(s.RefreshAfter)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Stale reports whether the cached tools are out of date: the server
+// announced a change, or they are older than RefreshAfter.
+func (s *Server) Stale() bool {
+	if s.ToolsStale {
+		return true
+	}
+	interval := s.RefreshInterval()
+	return interval > 0 && time.Since(s.UpdatedAt) > interval
+}
+
 // ExpandHeaders returns a copy of headers with env vars expanded
 func (s *Server) ExpandHeaders() map[string]string {
 	expanded := make(map[string]string, len(s.Headers))
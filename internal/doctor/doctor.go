@@ -0,0 +1,575 @@
+// Package doctor runs staged health and conformance checks against an MCP
+// server.
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mcp"
+	"github.com/juanibiapina/mcpli/internal/oauth"
+	"github.com/juanibiapina/mcpli/internal/transport"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// networkTimeout bounds DNS lookups, connections and plain HTTP requests.
+const networkTimeout = 10 * time.Second
+
+// certExpiryWarning is how long before expiry a certificate is reported.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// Check is the result of one diagnostic step.
+type Check struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Message    string `json:"message"`
+	Hint       string `json:"hint,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the result of all checks for a server.
+type Report struct {
+	Server  string         `json:"server"`
+	URL     string         `json:"url"`
+	Checks  []Check        `json:"checks"`
+	Summary map[Status]int `json:"summary"`
+}
+
+// Failed returns true if any check failed.
+func (r *Report) Failed() bool {
+	return r.Summary[Fail] > 0
+}
+
+// Target is the server to diagnose.
+type Target struct {
+	Name   string
+	Server *config.Server
+	// Headers are the server's configured headers, expanded, without the
+	// OAuth token.
+	Headers map[string]string
+	// Transport carries the server's TLS and proxy settings.
+	Transport http.RoundTripper
+	// Proxy is the proxy used for the server URL, if any.
+	Proxy *url.URL
+	// NewClient creates the MCP client for the session checks, so that the
+	// caller can record or replay them. When nil, a plain client using
+	// Transport is created.
+	NewClient func(url string, headers map[string]string, rt http.RoundTripper) *mcp.Client
+}
+
+// runner holds the state passed between checks.
+type runner struct {
+	target  Target
+	url     *url.URL
+	headers map[string]string
+
+	resolved    bool
+	connected   bool
+	authorized  bool
+	client      *mcp.Client
+	initialized bool
+	notifyErr   error
+}
+
+// Run runs all checks in order. Checks whose prerequisites failed are skipped.
+func Run(target Target) *Report {
+	r := &runner{target: target, headers: map[string]string{}}
+	for k, v := range target.Headers {
+		r.headers[k] = v
+	}
+
+	report := &Report{Server: target.Name, URL: target.Server.URL, Summary: map[Status]int{}}
+	steps := []struct {
+		name string
+		run  func() (Status, string, string)
+	}{
+		{"resolve", r.checkResolve},
+		{"connect", r.checkConnect},
+		{"tls", r.checkTLS},
+		{"oauth discovery", r.checkDiscovery},
+		{"token", r.checkToken},
+		{"initialize", r.checkInitialize},
+		{"initialized notification", r.checkInitializedNotification},
+		{"ping", r.checkPing},
+		{"tools/list", r.checkToolsList},
+		{"tool schemas", r.checkSchemas},
+		{"session", r.checkSession},
+	}
+	for _, step := range steps {
+		start := time.Now()
+		status, message, hint := step.run()
+		report.Checks = append(report.Checks, Check{
+			Name:       step.name,
+			Status:     status,
+			Message:    message,
+			Hint:       hint,
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+		report.Summary[status]++
+	}
+	return report
+}
+
+func (r *runner) checkResolve() (Status, string, string) {
+	parsed, err := url.Parse(r.target.Server.URL)
+	if err != nil || parsed.Host == "" && parsed.Scheme != "unix" {
+		return Fail, fmt.Sprintf("invalid server URL %q", r.target.Server.URL), "Fix the url of the server in the config file"
+	}
+	r.url = parsed
+
+	if parsed.Scheme == "unix" {
+		socketPath, _ := transport.ParseUnixURL(parsed)
+		info, err := os.Stat(socketPath)
+		if err != nil {
+			return Fail, fmt.Sprintf("socket %s: %v", socketPath, err), "Start the server or check the socket path in the URL"
+		}
+		if info.Mode()&os.ModeSocket == 0 {
+			return Fail, fmt.Sprintf("%s is not a unix socket", socketPath), "Check the socket path in the URL"
+		}
+		r.resolved = true
+		return Pass, fmt.Sprintf("unix socket %s", socketPath), ""
+	}
+
+	host := parsed.Hostname()
+	if net.ParseIP(host) != nil {
+		r.resolved = true
+		return Pass, fmt.Sprintf("%s is an IP address", host), ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		if r.target.Proxy != nil {
+			// The proxy resolves the host for us
+			r.resolved = true
+			return Warn, fmt.Sprintf("%s does not resolve locally: %v", host, err), "Requests go through the proxy, which resolves the host itself"
+		}
+		return Fail, fmt.Sprintf("%s: %v", host, err), "Check the hostname in the server URL and your DNS settings"
+	}
+	r.resolved = true
+	return Pass, fmt.Sprintf("%s -> %s", host, strings.Join(addrs, ", ")), ""
+}
+
+func (r *runner) checkConnect() (Status, string, string) {
+	if !r.resolved {
+		return Skip, "host did not resolve", ""
+	}
+
+	network, addr := "tcp", hostPort(r.url)
+	via := ""
+	switch {
+	case r.url.Scheme == "unix":
+		network = "unix"
+		addr, _ = transport.ParseUnixURL(r.url)
+	case r.target.Proxy != nil:
+		addr = hostPort(r.target.Proxy)
+		via = "proxy "
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout(network, addr, networkTimeout)
+	if err != nil {
+		return Fail, fmt.Sprintf("%s%s: %v", via, addr, err), "Check that the server is running and that no firewall blocks the connection"
+	}
+	conn.Close()
+	r.connected = true
+	return Pass, fmt.Sprintf("connected to %s%s in %s", via, addr, roundDuration(time.Since(start))), ""
+}
+
+func (r *runner) checkTLS() (Status, string, string) {
+	if !r.connected {
+		return Skip, "not connected", ""
+	}
+
+	switch r.url.Scheme {
+	case "unix":
+		return Skip, "unix socket, no TLS", ""
+	case "http":
+		if ip := net.ParseIP(r.url.Hostname()); ip != nil && ip.IsLoopback() || r.url.Hostname() == "localhost" {
+			return Skip, "plain HTTP on loopback", ""
+		}
+		return Warn, fmt.Sprintf("traffic to %s is not encrypted", r.url.Host), "Use an https:// URL if the server supports it"
+	}
+
+	// Any response completes the handshake. HEAD avoids opening the
+	// server's event stream.
+	resp, err := r.httpClient().Head(r.target.Server.URL)
+	if err != nil {
+		return Fail, err.Error(), tlsHint(err)
+	}
+	resp.Body.Close()
+
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return Warn, "no TLS connection state", ""
+	}
+	cert := resp.TLS.PeerCertificates[0]
+	message := fmt.Sprintf("%s, certificate for %s valid until %s", tls.VersionName(resp.TLS.Version), cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+
+	if r.target.Server.InsecureSkipVerify {
+		return Warn, message + ", verification disabled", "Remove insecure_skip_verify and pass --ca-file with the server's CA instead"
+	}
+	if time.Until(cert.NotAfter) < certExpiryWarning {
+		return Warn, message + " (expires soon)", "Renew the server certificate"
+	}
+	return Pass, message, ""
+}
+
+// tlsHint returns remediation for a failed TLS handshake.
+func tlsHint(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "pinned public key"):
+		return "The server's public key changed: verify the new certificate and update pinned_spki"
+	case strings.Contains(msg, "certificate signed by unknown authority"):
+		return "Pass --ca-file with the CA that signed the server certificate"
+	case strings.Contains(msg, "certificate is valid for"):
+		return "The certificate does not match the hostname in the server URL"
+	case strings.Contains(msg, "protocol version"):
+		return "The server does not support the minimum TLS version (min_tls_version)"
+	case strings.Contains(msg, "certificate required"):
+		return "The server requires a client certificate: pass --client-cert and --client-key"
+	}
+	return "Check the TLS settings of the server (ca_file, client_cert, min_tls_version)"
+}
+
+// httpClient returns a client for plain HTTP and OAuth requests to the
+// server, through its transport.
+func (r *runner) httpClient() *http.Client {
+	return &http.Client{Transport: r.target.Transport, Timeout: networkTimeout}
+}
+
+func (r *runner) checkDiscovery() (Status, string, string) {
+	server := r.target.Server
+	if !server.OAuth {
+		return Skip, "server does not use OAuth", ""
+	}
+	if !r.connected {
+		return Skip, "not connected", ""
+	}
+
+	meta, err := oauth.Discover(r.httpClient(), server.URL)
+	if err != nil {
+		return Fail, err.Error(), "The authorization server metadata (.well-known/oauth-authorization-server) could not be fetched"
+	}
+
+	message := fmt.Sprintf("issuer %s, token endpoint %s", meta.Issuer, meta.TokenEndpoint)
+	if server.GrantType != oauth.GrantClientCredentials && meta.AuthorizationEndpoint == "" {
+		return Fail, message + ", no authorization_endpoint", "Use the client credentials grant if the server only supports it"
+	}
+	if meta.RegistrationEndpoint == "" && server.ClientID == "" && !meta.ClientIDMetadataDocumentSupported {
+		return Warn, message + ", no dynamic client registration", "Register a client with the authorization server and pass --client-id"
+	}
+	return Pass, message, ""
+}
+
+func (r *runner) checkToken() (Status, string, string) {
+	server := r.target.Server
+	if !server.OAuth {
+		r.authorized = true
+		return Skip, "server does not use OAuth", ""
+	}
+
+	token, err := oauth.GetValidToken(r.httpClient(), server.URL, server.Identity, server.Issuer)
+	if err != nil {
+		return Fail, err.Error(), fmt.Sprintf("Run 'mcpli auth login %s'", r.target.Name)
+	}
+	r.headers["Authorization"] = "Bearer " + token
+	r.authorized = true
+
+	message := "valid token"
+	if server.Identity != "" {
+		message += fmt.Sprintf(" for identity %s", server.Identity)
+	}
+	if store, err := oauth.LoadStore(); err == nil {
+		if entry := store.Entry(server.URL, server.Identity, server.Issuer); entry != nil && !entry.ExpiresAt.IsZero() {
+			message += fmt.Sprintf(", expires in %s", time.Until(entry.ExpiresAt).Round(time.Second))
+		}
+	}
+	return Pass, message, ""
+}
+
+func (r *runner) checkInitialize() (Status, string, string) {
+	if !r.connected {
+		return Skip, "not connected", ""
+	}
+	if !r.authorized {
+		return Skip, "no valid token", ""
+	}
+
+	if r.target.NewClient != nil {
+		r.client = r.target.NewClient(r.target.Server.URL, r.headers, r.target.Transport)
+	} else {
+		r.client = mcp.NewClient(r.target.Server.URL, r.headers)
+		r.client.SetTransport(r.target.Transport)
+	}
+
+	result, err := r.client.Initialize()
+	var notifyErr *mcp.NotificationError
+	if errors.As(err, &notifyErr) {
+		// initialize itself succeeded
+		r.notifyErr = notifyErr.Err
+		r.initialized = true
+		return Pass, "initialize succeeded", ""
+	}
+	if err != nil {
+		return Fail, err.Error(), requestHint(r.target.Name, err)
+	}
+	r.initialized = true
+
+	message := fmt.Sprintf("%s %s, protocol %s", result.ServerInfo.Name, result.ServerInfo.Version, result.ProtocolVersion)
+	if result.ProtocolVersion == "" {
+		return Warn, message, "The server did not announce a protocol version"
+	}
+	return Pass, message, ""
+}
+
+// requestHint returns remediation for a failed MCP request.
+func requestHint(name string, err error) string {
+	var unauthorized *mcp.UnauthorizedError
+	var forbidden *mcp.ForbiddenError
+	var httpErr *mcp.HTTPError
+	switch {
+	case errors.As(err, &unauthorized):
+		return fmt.Sprintf("Run 'mcpli auth login %s', or check the configured headers", name)
+	case errors.As(err, &forbidden):
+		return "The credentials lack permission; check the requested scopes"
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound:
+		return "Check the path of the server URL"
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusMethodNotAllowed:
+		return "The URL does not look like a streamable HTTP MCP endpoint"
+	}
+	return ""
+}
+
+func (r *runner) checkInitializedNotification() (Status, string, string) {
+	if !r.initialized {
+		return Skip, "not initialized", ""
+	}
+	if r.notifyErr != nil {
+		return Fail, r.notifyErr.Error(), "The server must accept notifications/initialized with 202 Accepted"
+	}
+	return Pass, "accepted", ""
+}
+
+func (r *runner) checkPing() (Status, string, string) {
+	if !r.initialized {
+		return Skip, "not initialized", ""
+	}
+
+	start := time.Now()
+	err := r.client.Ping()
+	elapsed := roundDuration(time.Since(start))
+
+	var rpcErr *mcp.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == -32601 {
+		return Warn, "server does not implement ping", "ping is required by the MCP specification"
+	}
+	if err != nil {
+		return Fail, err.Error(), requestHint(r.target.Name, err)
+	}
+	return Pass, fmt.Sprintf("%s round trip", elapsed), ""
+}
+
+func (r *runner) checkToolsList() (Status, string, string) {
+	if !r.initialized {
+		return Skip, "not initialized", ""
+	}
+
+	result, err := r.client.ListTools()
+	var paginationErr *mcp.PaginationError
+	if errors.As(err, &paginationErr) {
+		return Fail, err.Error(), "The server's pagination never ends"
+	}
+	if err != nil {
+		return Fail, err.Error(), requestHint(r.target.Name, err)
+	}
+	tools := result.Tools
+
+	message := fmt.Sprintf("%d tools in %d page(s)", len(tools), result.Pages)
+
+	names := map[string]bool{}
+	var duplicates []string
+	for _, tool := range tools {
+		if names[tool.Name] {
+			duplicates = append(duplicates, tool.Name)
+		}
+		names[tool.Name] = true
+	}
+	if len(duplicates) > 0 {
+		return Warn, message + fmt.Sprintf(", duplicate names: %s", strings.Join(duplicates, ", ")), "Tool names must be unique"
+	}
+
+	cached := map[string]bool{}
+	for _, tool := range r.target.Server.Tools {
+		cached[tool.Name] = true
+	}
+	var added, removed []string
+	for name := range names {
+		if !cached[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range cached {
+		if !names[name] {
+			removed = append(removed, name)
+		}
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		return Warn, message + fmt.Sprintf(", cache is outdated (%d new, %d removed)", len(added), len(removed)), fmt.Sprintf("Run 'mcpli update %s'", r.target.Name)
+	}
+	return Pass, message + ", matching the cache", ""
+}
+
+func (r *runner) checkSchemas() (Status, string, string) {
+	tools := r.target.Server.Tools
+	if len(tools) == 0 {
+		return Warn, "no cached tools", fmt.Sprintf("Run 'mcpli update %s'", r.target.Name)
+	}
+
+	var errs, warns []string
+	for _, tool := range tools {
+		toolErrs, toolWarns := ValidateTool(tool)
+		for _, e := range toolErrs {
+			errs = append(errs, tool.Name+": "+e)
+		}
+		for _, w := range toolWarns {
+			warns = append(warns, tool.Name+": "+w)
+		}
+	}
+
+	switch {
+	case len(errs) > 0:
+		return Fail, summarize(append(errs, warns...)), "Tools with invalid schemas cannot be called reliably; report it to the server's maintainers"
+	case len(warns) > 0:
+		return Warn, summarize(warns), ""
+	}
+	return Pass, fmt.Sprintf("%d tool schemas valid", len(tools)), ""
+}
+
+// summarize joins the first few problems.
+func summarize(problems []string) string {
+	const max = 5
+	if len(problems) <= max {
+		return strings.Join(problems, "; ")
+	}
+	return strings.Join(problems[:max], "; ") + fmt.Sprintf("; and %d more", len(problems)-max)
+}
+
+func (r *runner) checkSession() (Status, string, string) {
+	if !r.initialized {
+		return Skip, "not initialized", ""
+	}
+
+	sessionID := r.client.SessionID()
+	if sessionID == "" {
+		return Pass, "stateless server (no Mcp-Session-Id)", ""
+	}
+
+	var notes []string
+	status := Pass
+	hint := ""
+
+	code, err := r.postPing("")
+	switch {
+	case err != nil:
+		return Fail, err.Error(), ""
+	case code == http.StatusBadRequest:
+		notes = append(notes, "requests without Mcp-Session-Id are rejected")
+	default:
+		status = Warn
+		notes = append(notes, fmt.Sprintf("a request without Mcp-Session-Id got status %d instead of 400", code))
+	}
+
+	err = r.client.Terminate()
+	var httpErr *mcp.HTTPError
+	switch {
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusMethodNotAllowed:
+		notes = append(notes, "clients cannot terminate sessions (405)")
+	case err != nil:
+		status = Warn
+		notes = append(notes, fmt.Sprintf("DELETE failed: %v", err))
+	default:
+		code, err := r.postPing(sessionID)
+		switch {
+		case err != nil:
+			return Fail, err.Error(), ""
+		case code == http.StatusNotFound:
+			notes = append(notes, "terminated session returns 404")
+		default:
+			status = Warn
+			notes = append(notes, fmt.Sprintf("terminated session got status %d instead of 404", code))
+			hint = "Clients detect expired sessions by a 404 response and re-initialize"
+		}
+	}
+
+	return status, "session " + sessionID + ": " + strings.Join(notes, ", "), hint
+}
+
+// postPing sends a ping with the given session id (none if empty) and returns
+// the HTTP status.
+func (r *runner) postPing(sessionID string) (int, error) {
+	body := []byte(`{"jsonrpc":"2.0","id":99,"method":"ping"}`)
+	req, err := http.NewRequest("POST", r.target.Server.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// roundDuration rounds to milliseconds, or microseconds below a millisecond.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// hostPort returns the host and port of a URL, with the scheme's default port.
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch u.Scheme {
+	case "https":
+		return net.JoinHostPort(u.Hostname(), "443")
+	case "socks5", "socks5h":
+		return net.JoinHostPort(u.Hostname(), "1080")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
@@ -0,0 +1,177 @@
+package doctor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mock"
+)
+
+func mockTools() []config.Tool {
+	return []config.Tool{
+		{Name: "search", InputSchema: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`)},
+		{Name: "get_cart", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "checkout", InputSchema: json.RawMessage(`{"type":"object"}`)},
+	}
+}
+
+func runAgainst(t *testing.T, handler http.Handler, tools []config.Tool) *Report {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return Run(Target{
+		Name:      "test",
+		Server:    &config.Server{URL: server.URL + "/mcp", Tools: tools},
+		Transport: http.DefaultTransport,
+	})
+}
+
+func checkStatus(t *testing.T, report *Report, name string) Check {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no %q check in report", name)
+	return Check{}
+}
+
+func TestRun_HealthyServer(t *testing.T) {
+	spec := &mock.Spec{ServerInfo: config.ServerInfo{Name: "mock", Version: "1.0"}, Tools: mockTools()}
+	report := runAgainst(t, mock.New(spec, mock.Options{PageSize: 2}), mockTools())
+
+	want := map[string]Status{
+		"resolve":                  Pass,
+		"connect":                  Pass,
+		"tls":                      Skip,
+		"oauth discovery":          Skip,
+		"token":                    Skip,
+		"initialize":               Pass,
+		"initialized notification": Pass,
+		"ping":                     Pass,
+		"tools/list":               Pass,
+		"tool schemas":             Pass,
+		"session":                  Pass,
+	}
+	for name, status := range want {
+		if check := checkStatus(t, report, name); check.Status != status {
+			t.Errorf("%s: status %s (%s), want %s", name, check.Status, check.Message, status)
+		}
+	}
+	if report.Failed() {
+		t.Error("report should not fail")
+	}
+
+	if msg := checkStatus(t, report, "tools/list").Message; !strings.Contains(msg, "3 tools in 2 page(s)") {
+		t.Errorf("tools/list message = %q", msg)
+	}
+	if msg := checkStatus(t, report, "session").Message; !strings.Contains(msg, "terminated session returns 404") {
+		t.Errorf("session message = %q", msg)
+	}
+}
+
+func TestRun_OutdatedCache(t *testing.T) {
+	spec := &mock.Spec{Tools: mockTools()}
+	report := runAgainst(t, mock.New(spec, mock.Options{}), mockTools()[:1])
+
+	check := checkStatus(t, report, "tools/list")
+	if check.Status != Warn || !strings.Contains(check.Hint, "mcpli update test") {
+		t.Errorf("tools/list = %+v, want a warning suggesting update", check)
+	}
+}
+
+func TestRun_Unauthorized(t *testing.T) {
+	report := runAgainst(t, mock.New(&mock.Spec{}, mock.Options{RequireToken: "secret"}), nil)
+
+	check := checkStatus(t, report, "initialize")
+	if check.Status != Fail || !strings.Contains(check.Hint, "mcpli auth login test") {
+		t.Errorf("initialize = %+v, want a failure suggesting login", check)
+	}
+	if check := checkStatus(t, report, "ping"); check.Status != Skip {
+		t.Errorf("ping should be skipped after initialize failed, got %s", check.Status)
+	}
+	if !report.Failed() {
+		t.Error("report should fail")
+	}
+}
+
+func TestRun_RejectedNotificationAndMissingPing(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		w.Header().Set("Content-Type", "application/json")
+		switch msg.Method {
+		case "initialize":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","serverInfo":{"name":"s","version":"1"}}}`))
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusBadRequest)
+		case "tools/list":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":2,"result":{"tools":[]}}`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"method not found"}}`))
+		}
+	})
+	report := runAgainst(t, handler, nil)
+
+	if check := checkStatus(t, report, "initialize"); check.Status != Pass {
+		t.Errorf("initialize = %+v, want pass", check)
+	}
+	if check := checkStatus(t, report, "initialized notification"); check.Status != Fail {
+		t.Errorf("initialized notification = %+v, want fail", check)
+	}
+	if check := checkStatus(t, report, "ping"); check.Status != Warn {
+		t.Errorf("ping = %+v, want warn", check)
+	}
+	if check := checkStatus(t, report, "session"); check.Status != Pass || !strings.Contains(check.Message, "stateless") {
+		t.Errorf("session = %+v, want stateless pass", check)
+	}
+}
+
+func TestRun_UnresolvableHost(t *testing.T) {
+	report := Run(Target{
+		Name:      "test",
+		Server:    &config.Server{URL: "https://mcp.invalid/mcp"},
+		Transport: http.DefaultTransport,
+	})
+
+	if check := checkStatus(t, report, "resolve"); check.Status != Fail {
+		t.Errorf("resolve = %+v, want fail", check)
+	}
+	for _, name := range []string{"connect", "tls", "initialize", "session"} {
+		if check := checkStatus(t, report, name); check.Status != Skip {
+			t.Errorf("%s = %s, want skip", name, check.Status)
+		}
+	}
+}
+
+func TestValidateTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      config.Tool
+		wantErrs  int
+		wantWarns int
+	}{
+		{"valid", config.Tool{InputSchema: json.RawMessage(`{"type":"object","properties":{"a":{"type":["string","null"]}},"required":["a"]}`)}, 0, 0},
+		{"missing schema", config.Tool{}, 0, 1},
+		{"not an object", config.Tool{InputSchema: json.RawMessage(`[]`)}, 1, 0},
+		{"wrong type", config.Tool{InputSchema: json.RawMessage(`{"type":"string"}`)}, 0, 1},
+		{"invalid property type", config.Tool{InputSchema: json.RawMessage(`{"type":"object","properties":{"a":{"type":"text"}}}`)}, 1, 0},
+		{"undefined required", config.Tool{InputSchema: json.RawMessage(`{"type":"object","required":["a"]}`)}, 0, 1},
+		{"invalid output schema", config.Tool{InputSchema: json.RawMessage(`{"type":"object"}`), OutputSchema: json.RawMessage(`{"type":"object","properties":[]}`)}, 1, 0},
+	}
+	for _, tt := range tests {
+		errs, warns := ValidateTool(tt.tool)
+		if len(errs) != tt.wantErrs || len(warns) != tt.wantWarns {
+			t.Errorf("%s: errors %v, warnings %v; want %d errors, %d warnings", tt.name, errs, warns, tt.wantErrs, tt.wantWarns)
+		}
+	}
+}
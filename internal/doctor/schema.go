@@ -0,0 +1,109 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+)
+
+// jsonSchemaTypes are the valid values of a JSON Schema "type".
+var jsonSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// ValidateTool checks a tool's input and output schemas. Errors make the
+// schema unusable; warnings are spec deviations clients can work around.
+func ValidateTool(tool config.Tool) (errs, warns []string) {
+	if len(tool.InputSchema) == 0 {
+		warns = append(warns, "no inputSchema")
+	} else {
+		e, w := validateObjectSchema("inputSchema", tool.InputSchema)
+		errs, warns = append(errs, e...), append(warns, w...)
+	}
+	if len(tool.OutputSchema) > 0 {
+		e, w := validateObjectSchema("outputSchema", tool.OutputSchema)
+		errs, warns = append(errs, e...), append(warns, w...)
+	}
+	return errs, warns
+}
+
+// validateObjectSchema checks a schema that must describe a JSON object.
+func validateObjectSchema(field string, raw json.RawMessage) (errs, warns []string) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return []string{fmt.Sprintf("%s is not a JSON object: %v", field, err)}, nil
+	}
+
+	if t, _ := schema["type"].(string); t != "object" {
+		warns = append(warns, fmt.Sprintf("%s type is %v, must be \"object\"", field, schema["type"]))
+	}
+
+	properties := map[string]interface{}{}
+	if raw, ok := schema["properties"]; ok {
+		props, ok := raw.(map[string]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s properties is not an object", field)), warns
+		}
+		properties = props
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch prop := properties[name].(type) {
+		case bool:
+		case map[string]interface{}:
+			if bad := invalidTypes(prop["type"]); len(bad) > 0 {
+				errs = append(errs, fmt.Sprintf("%s property %q has invalid type %v", field, name, bad))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s property %q is not a schema", field, name))
+		}
+	}
+
+	if raw, ok := schema["required"]; ok {
+		required, ok := raw.([]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s required is not an array", field)), warns
+		}
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s required entry %v is not a string", field, r))
+				continue
+			}
+			if _, defined := properties[name]; !defined {
+				warns = append(warns, fmt.Sprintf("%s requires undefined property %q", field, name))
+			}
+		}
+	}
+
+	return errs, warns
+}
+
+// invalidTypes returns the invalid type names of a "type" keyword.
+func invalidTypes(t interface{}) []interface{} {
+	var bad []interface{}
+	switch v := t.(type) {
+	case nil:
+	case string:
+		if !jsonSchemaTypes[v] {
+			bad = append(bad, v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if name, ok := item.(string); !ok || !jsonSchemaTypes[name] {
+				bad = append(bad, item)
+			}
+		}
+	default:
+		bad = append(bad, v)
+	}
+	return bad
+}
@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Replay modes.
+const (
+	// ReplayStrict requires requests in the recorded order with the
+	// recorded params, notifications included.
+	ReplayStrict = "strict"
+	// ReplayLenient serves the response of any recorded request with the
+	// same method and params, falling back to the same method, and ignores
+	// notifications.
+	ReplayLenient = "lenient"
+)
+
+// cassetteVersion is the version of the cassette file format.
+const cassetteVersion = 1
+
+// Exchange is a recorded JSON-RPC request or notification and its response.
+type Exchange struct {
+	Method       string          `json:"method"`
+	Params       json.RawMessage `json:"params,omitempty"`
+	Notification bool            `json:"notification,omitempty"`
+	Result       json.RawMessage `json:"result,omitempty"`
+	Error        json.RawMessage `json:"error,omitempty"`
+}
+
+// Cassette is a recording of the JSON-RPC exchanges of a session.
+type Cassette struct {
+	Version   int        `json:"version"`
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Recorder records the exchanges of clients into a cassette file. The file is
+// rewritten after each exchange, so it is complete even if the command fails.
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	cassette Cassette
+}
+
+// NewRecorder creates an empty cassette at path.
+func NewRecorder(path string) (*Recorder, error) {
+	r := &Recorder{path: path, cassette: Cassette{Version: cassetteVersion, Exchanges: []Exchange{}}}
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) record(e Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Exchanges = append(r.cassette.Exchanges, e)
+	return r.save()
+}
+
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Replayer serves responses from a cassette instead of the network.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette Cassette
+	mode     string
+	next     int
+	used     []bool
+}
+
+// NewReplayer loads the cassette at path for replay in the given mode.
+func NewReplayer(path, mode string) (*Replayer, error) {
+	if mode != ReplayStrict && mode != ReplayLenient {
+		return nil, fmt.Errorf("invalid replay mode %q (expected %s or %s)", mode, ReplayStrict, ReplayLenient)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d", cassette.Version)
+	}
+
+	return &Replayer{cassette: cassette, mode: mode, used: make([]bool, len(cassette.Exchanges))}, nil
+}
+
+// Remaining returns the number of recorded exchanges not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for i, e := range r.cassette.Exchanges {
+		if !r.used[i] && !(r.mode == ReplayLenient && e.Notification) {
+			n++
+		}
+	}
+	return n
+}
+
+// reply returns the recorded exchange for a request or notification. In
+// lenient mode notifications return nil.
+func (r *Replayer) reply(method string, params json.RawMessage, notification bool) (*Exchange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mode == ReplayStrict {
+		if r.next >= len(r.cassette.Exchanges) {
+			return nil, fmt.Errorf("replay: unexpected %s, the cassette has no more exchanges", method)
+		}
+		e := &r.cassette.Exchanges[r.next]
+		if e.Method != method || e.Notification != notification || !paramsMatch(method, e.Params, params) {
+			return nil, fmt.Errorf("replay: expected %s %s, got %s %s", e.Method, canonicalParams(e.Params), method, canonicalParams(params))
+		}
+		r.used[r.next] = true
+		r.next++
+		return e, nil
+	}
+
+	if notification {
+		return nil, nil
+	}
+
+	// Prefer an unused exchange with the same params, then any with the
+	// same params (repeated calls), then an unused one with the same method.
+	candidates := []func(i int, e *Exchange) bool{
+		func(i int, e *Exchange) bool { return !r.used[i] && paramsMatch(method, e.Params, params) },
+		func(i int, e *Exchange) bool { return paramsMatch(method, e.Params, params) },
+		func(i int, e *Exchange) bool { return !r.used[i] },
+	}
+	for _, match := range candidates {
+		for i := range r.cassette.Exchanges {
+			e := &r.cassette.Exchanges[i]
+			if e.Method == method && !e.Notification && match(i, e) {
+				r.used[i] = true
+				return e, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("replay: no recorded response for %s %s", method, canonicalParams(params))
+}
+
+// paramsMatch compares request params as JSON values. initialize matches on
+// the method alone, since its params carry the client version.
+func paramsMatch(method string, a, b json.RawMessage) bool {
+	if method == "initialize" {
+		return true
+	}
+	return canonicalParams(a) == canonicalParams(b)
+}
+
+// canonicalParams returns params as compact JSON with sorted object keys.
+func canonicalParams(params json.RawMessage) string {
+	trimmed := bytes.TrimSpace(params)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return "{}"
+	}
+	var v interface{}
+	if err := json.Unmarshal(trimmed, &v); err != nil {
+		return string(trimmed)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return string(trimmed)
+	}
+	return string(canonical)
+}
+
+// response converts a recorded exchange back into a JSON-RPC response. The
+// result is compacted, since the cassette stores it indented.
+func (e *Exchange) response(id int) (*jsonRPCResponse, error) {
+	resp := &jsonRPCResponse{JSONRPC: "2.0", ID: id}
+	if len(e.Result) > 0 {
+		var result bytes.Buffer
+		if err := json.Compact(&result, e.Result); err != nil {
+			return nil, fmt.Errorf("invalid recorded result for %s: %w", e.Method, err)
+		}
+		resp.Result = result.Bytes()
+	}
+	if len(e.Error) > 0 {
+		resp.Error = &RPCError{}
+		if err := json.Unmarshal(e.Error, resp.Error); err != nil {
+			return nil, fmt.Errorf("invalid recorded error for %s: %w", e.Method, err)
+		}
+	}
+	return resp, nil
+}
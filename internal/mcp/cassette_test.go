@@ -0,0 +1,211 @@
+package mcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordSession records initialize and tools/list against the strict test
+// server.
+func recordSession(t *testing.T) string {
+	t.Helper()
+	server := strictServer(t)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	client := NewClient(server.URL, nil)
+	client.SetRecorder(recorder)
+	if _, err := client.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	return path
+}
+
+func TestRecorder_WritesExchanges(t *testing.T) {
+	path := recordSession(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		t.Fatalf("invalid cassette: %v", err)
+	}
+
+	var methods []string
+	for _, e := range cassette.Exchanges {
+		methods = append(methods, e.Method)
+	}
+	if got := strings.Join(methods, ","); got != "initialize,notifications/initialized,tools/list" {
+		t.Errorf("recorded methods = %s", got)
+	}
+	if !cassette.Exchanges[1].Notification {
+		t.Error("notifications/initialized not recorded as a notification")
+	}
+	var tools ListToolsResult
+	if err := json.Unmarshal(cassette.Exchanges[2].Result, &tools); err != nil || len(tools.Tools) != 1 {
+		t.Errorf("tools/list result not recorded: %s", cassette.Exchanges[2].Result)
+	}
+}
+
+func TestReplayer_StrictServesRecordedSession(t *testing.T) {
+	path := recordSession(t)
+
+	replayer, err := NewReplayer(path, ReplayStrict)
+	if err != nil {
+		t.Fatalf("NewReplayer failed: %v", err)
+	}
+
+	// No server: every response comes from the cassette
+	client := NewClient("http://127.0.0.1:0/unreachable", nil)
+	client.SetReplayer(replayer)
+	info, err := client.Initialize()
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if info.ServerInfo.Name != "s" {
+		t.Errorf("server name = %q, want s", info.ServerInfo.Name)
+	}
+	result, err := client.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(result.Tools) != 1 || result.Tools[0].Name != "t" {
+		t.Errorf("unexpected tools %+v", result.Tools)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("Remaining() = %d, want 0", n)
+	}
+
+	if _, err := client.ListTools(); err == nil || !strings.Contains(err.Error(), "no more exchanges") {
+		t.Errorf("expected exhausted cassette error, got %v", err)
+	}
+}
+
+func writeCassette(t *testing.T, exchanges ...Exchange) string {
+	t.Helper()
+	data, err := json.Marshal(Cassette{Version: cassetteVersion, Exchanges: exchanges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func toolCall(args, result string) Exchange {
+	return Exchange{
+		Method: "tools/call",
+		Params: json.RawMessage(`{"name":"search","arguments":` + args + `}`),
+		Result: json.RawMessage(result),
+	}
+}
+
+func TestReplayer_StrictRejectsMismatch(t *testing.T) {
+	path := writeCassette(t, toolCall(`{"q":"milk"}`, `{"content":[]}`))
+	replayer, err := NewReplayer(path, ReplayStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient("http://127.0.0.1:0/unreachable", nil)
+	client.SetReplayer(replayer)
+	_, err = client.CallTool("search", json.RawMessage(`{"q":"bread"}`))
+	if err == nil || !strings.Contains(err.Error(), "expected tools/call") {
+		t.Errorf("expected mismatch error, got %v", err)
+	}
+}
+
+func TestReplayer_StrictMatchesParamsIgnoringKeyOrder(t *testing.T) {
+	path := writeCassette(t, toolCall(`{"q":"milk","limit":5}`, `{"content":[{"type":"text","text":"ok"}]}`))
+	replayer, err := NewReplayer(path, ReplayStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient("http://127.0.0.1:0/unreachable", nil)
+	client.SetReplayer(replayer)
+	result, err := client.CallTool("search", json.RawMessage(`{ "limit": 5, "q": "milk" }`))
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if !strings.Contains(string(result), `"ok"`) {
+		t.Errorf("unexpected result %s", result)
+	}
+}
+
+func TestReplayer_Lenient(t *testing.T) {
+	path := writeCassette(t,
+		Exchange{Method: "notifications/initialized", Notification: true},
+		toolCall(`{"q":"milk"}`, `{"content":[{"type":"text","text":"milk"}]}`),
+		toolCall(`{"q":"bread"}`, `{"content":[{"type":"text","text":"bread"}]}`),
+	)
+	replayer, err := NewReplayer(path, ReplayLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient("http://127.0.0.1:0/unreachable", nil)
+	client.SetReplayer(replayer)
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"q":"bread"}`, "bread"}, // out of order
+		{`{"q":"bread"}`, "bread"}, // repeated
+		{`{"q":"eggs"}`, "milk"},   // same method, unused exchange
+	}
+	for _, tt := range tests {
+		result, err := client.CallTool("search", json.RawMessage(tt.args))
+		if err != nil {
+			t.Fatalf("CallTool(%s) failed: %v", tt.args, err)
+		}
+		if !strings.Contains(string(result), `"`+tt.want+`"`) {
+			t.Errorf("CallTool(%s) = %s, want the %s response", tt.args, result, tt.want)
+		}
+	}
+
+	if _, err := client.ListTools(); err == nil {
+		t.Error("expected error for a method missing from the cassette")
+	}
+}
+
+func TestReplayer_RecordedError(t *testing.T) {
+	path := writeCassette(t, Exchange{
+		Method: "tools/list",
+		Params: json.RawMessage(`{}`),
+		Error:  json.RawMessage(`{"code":-32601,"message":"not supported"}`),
+	})
+	replayer, err := NewReplayer(path, ReplayStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient("http://127.0.0.1:0/unreachable", nil)
+	client.SetReplayer(replayer)
+	if _, err := client.ListTools(); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected recorded server error, got %v", err)
+	}
+}
+
+func TestNewReplayer_InvalidMode(t *testing.T) {
+	path := writeCassette(t)
+	if _, err := NewReplayer(path, "fuzzy"); err == nil {
+		t.Error("expected error for invalid replay mode")
+	}
+}
@@ -19,18 +19,38 @@ const (
 // UnauthorizedError is returned when the server responds with 401.
 type UnauthorizedError struct {
 	Body string
+	// WWWAuthenticate is the raw WWW-Authenticate header of the response.
+	WWWAuthenticate string
 }
 
 func (e *UnauthorizedError) Error() string {
 	return fmt.Sprintf("server returned 401 Unauthorized: %s", e.Body)
 }
 
+// ForbiddenError is returned when the server responds with 403, e.g. when
+// the access token lacks a scope required for the request.
+type ForbiddenError struct {
+	Body string
+	// WWWAuthenticate is the raw WWW-Authenticate header of the response.
+	WWWAuthenticate string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("server returned 403 Forbidden: %s", e.Body)
+}
+
 // Client is an MCP HTTP/SSE client
 type Client struct {
 	URL       string
 	Headers   map[string]string
 	client    *http.Client
 	sessionID string
+	recorder  *Recorder
+	replayer  *Replayer
+
+	// toolsChanged is set when the server sends
+	// notifications/tools/list_changed during the session.
+	toolsChanged bool
 }
 
 // NewClient creates a new MCP client
@@ -64,6 +84,34 @@ func NewClient(url string, headers map[string]string) *Client {
 	}
 }
 
+// SetTransport sets the HTTP transport used for requests, e.g. to configure TLS.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.client.Transport = rt
+}
+
+// SetRecorder records the JSON-RPC exchanges of the client into a cassette.
+func (c *Client) SetRecorder(r *Recorder) {
+	c.recorder = r
+}
+
+// SetReplayer serves responses from a cassette instead of the network.
+func (c *Client) SetReplayer(r *Replayer) {
+	c.replayer = r
+}
+
+// Clone returns a client for a new session with the same URL, headers,
+// transport, recorder and replayer. The clone can be used concurrently with
+// the original.
+func (c *Client) Clone() *Client {
+	return &Client{
+		URL:      c.URL,
+		Headers:  c.Headers,
+		client:   c.client,
+		recorder: c.recorder,
+		replayer: c.replayer,
+	}
+}
+
 // jsonRPCRequest represents a JSON-RPC 2.0 request
 type jsonRPCRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
@@ -77,14 +125,43 @@ type jsonRPCResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
 	ID      int             `json:"id"`
 	Result  json.RawMessage `json:"result,omitempty"`
-	Error   *jsonRPCError   `json:"error,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
 }
 
-type jsonRPCError struct {
+// RPCError is a JSON-RPC error returned by the server.
+type RPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
 
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("server error: %s", e.Message)
+}
+
+// HTTPError is returned when the server responds with an unexpected status.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("server returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// NotificationError is returned when the server rejects a notification.
+type NotificationError struct {
+	Method string
+	Err    error
+}
+
+func (e *NotificationError) Error() string {
+	return fmt.Sprintf("failed to send %s notification: %v", strings.TrimPrefix(e.Method, "notifications/"), e.Err)
+}
+
+func (e *NotificationError) Unwrap() error {
+	return e.Err
+}
+
 // ServerInfo contains server metadata from initialize response
 type ServerInfo struct {
 	Name    string `json:"name"`
@@ -99,14 +176,18 @@ type InitializeResult struct {
 
 // Tool represents an MCP tool definition
 type Tool struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	InputSchema  json.RawMessage `json:"inputSchema,omitempty"`
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
 }
 
 // ListToolsResult is the result of a tools/list call
 type ListToolsResult struct {
-	Tools []Tool `json:"tools"`
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Pages is the number of pages ListTools requested.
+	Pages int `json:"-"`
 }
 
 // newRequest builds a POST request carrying the given JSON body, with the
@@ -144,8 +225,56 @@ func (c *Client) captureSession(resp *http.Response) {
 	}
 }
 
-// doRequest sends a JSON-RPC request and parses the SSE response
+// checkAuthStatus returns an UnauthorizedError or ForbiddenError when the
+// response status is 401 or 403, and nil otherwise.
+func checkAuthStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		body, _ := io.ReadAll(resp.Body)
+		return &UnauthorizedError{Body: string(body), WWWAuthenticate: resp.Header.Get("WWW-Authenticate")}
+	case http.StatusForbidden:
+		body, _ := io.ReadAll(resp.Body)
+		return &ForbiddenError{Body: string(body), WWWAuthenticate: resp.Header.Get("WWW-Authenticate")}
+	}
+	return nil
+}
+
+// doRequest sends a JSON-RPC request, or replays it from the cassette, and
+// records the response when recording.
 func (c *Client) doRequest(method string, params interface{}, id int) (*jsonRPCResponse, error) {
+	if c.replayer == nil && c.recorder == nil {
+		return c.sendRequest(method, params, id)
+	}
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if c.replayer != nil {
+		exchange, err := c.replayer.reply(method, rawParams, false)
+		if err != nil {
+			return nil, err
+		}
+		return exchange.response(id)
+	}
+
+	resp, err := c.sendRequest(method, params, id)
+	if err != nil {
+		return nil, err
+	}
+	exchange := Exchange{Method: method, Params: rawParams, Result: resp.Result}
+	if resp.Error != nil {
+		exchange.Error, _ = json.Marshal(resp.Error)
+	}
+	if err := c.recorder.record(exchange); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sendRequest sends a JSON-RPC request and parses the SSE response
+func (c *Client) sendRequest(method string, params interface{}, id int) (*jsonRPCResponse, error) {
 	req := jsonRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
@@ -171,26 +300,50 @@ func (c *Client) doRequest(method string, params interface{}, id int) (*jsonRPCR
 
 	c.captureSession(resp)
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, &UnauthorizedError{Body: string(body)}
+	if err := checkAuthStatus(resp); err != nil {
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// Parse response based on content type
 	contentType := resp.Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "text/event-stream") {
-		return parseSSEResponse(resp.Body)
+		return parseSSEResponse(resp.Body, c.handleNotification)
 	}
 	return parseJSONResponse(resp.Body)
 }
 
-// doNotify sends a JSON-RPC notification (no id, no response body expected).
+// doNotify sends a JSON-RPC notification, or checks it against the cassette,
+// and records it when recording.
 func (c *Client) doNotify(method string, params interface{}) error {
+	var rawParams json.RawMessage
+	if params != nil && (c.replayer != nil || c.recorder != nil) {
+		var err error
+		if rawParams, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("failed to marshal notification: %w", err)
+		}
+	}
+
+	if c.replayer != nil {
+		_, err := c.replayer.reply(method, rawParams, true)
+		return err
+	}
+
+	if err := c.sendNotify(method, params); err != nil {
+		return err
+	}
+	if c.recorder != nil {
+		return c.recorder.record(Exchange{Method: method, Params: rawParams, Notification: true})
+	}
+	return nil
+}
+
+// sendNotify sends a JSON-RPC notification (no id, no response body expected).
+func (c *Client) sendNotify(method string, params interface{}) error {
 	notification := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"method":  method,
@@ -220,15 +373,14 @@ func (c *Client) doNotify(method string, params interface{}) error {
 
 	c.captureSession(resp)
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		body, _ := io.ReadAll(resp.Body)
-		return &UnauthorizedError{Body: string(body)}
+	if err := checkAuthStatus(resp); err != nil {
+		return err
 	}
 
 	// Spec mandates 202 Accepted with an empty body; accept 200 too.
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -249,8 +401,32 @@ func parseJSONResponse(r io.Reader) (*jsonRPCResponse, error) {
 	return &resp, nil
 }
 
-// parseSSEResponse extracts JSON-RPC response from SSE format
-func parseSSEResponse(r io.Reader) (*jsonRPCResponse, error) {
+// ToolsListChanged is the notification a server sends when its tools change.
+const ToolsListChanged = "notifications/tools/list_changed"
+
+// handleNotification handles a notification the server sent in an SSE stream.
+func (c *Client) handleNotification(method string) {
+	if method == ToolsListChanged {
+		c.toolsChanged = true
+	}
+}
+
+// ToolsChanged reports whether the server announced a change to its tools
+// since the session started or since the last ListTools call.
+func (c *Client) ToolsChanged() bool {
+	return c.toolsChanged
+}
+
+// sseMessage tells the messages of an SSE stream apart: notifications and
+// server requests have a method, responses don't.
+type sseMessage struct {
+	Method string           `json:"method"`
+	ID     *json.RawMessage `json:"id"`
+}
+
+// parseSSEResponse extracts JSON-RPC response from SSE format. Notifications
+// sent before the response are passed to notify; server requests are skipped.
+func parseSSEResponse(r io.Reader, notify func(method string)) (*jsonRPCResponse, error) {
 	scanner := bufio.NewScanner(r)
 	
 	// Increase buffer size for large responses
@@ -263,6 +439,14 @@ func parseSSEResponse(r io.Reader) (*jsonRPCResponse, error) {
 		if strings.HasPrefix(line, "data: ") {
 			dataLine := strings.TrimPrefix(line, "data: ")
 			
+			var msg sseMessage
+			if err := json.Unmarshal([]byte(dataLine), &msg); err == nil && msg.Method != "" {
+				if msg.ID == nil && notify != nil {
+					notify(msg.Method)
+				}
+				continue
+			}
+
 			// Try to parse as JSON-RPC response
 			var resp jsonRPCResponse
 			if err := json.Unmarshal([]byte(dataLine), &resp); err != nil {
@@ -310,32 +494,83 @@ func (c *Client) Initialize() (*InitializeResult, error) {
 	// Complete the lifecycle handshake: servers that enforce the MCP
 	// initialization lifecycle reject method calls until this arrives.
 	if err := c.doNotify("notifications/initialized", nil); err != nil {
-		return nil, fmt.Errorf("failed to send initialized notification: %w", err)
+		return nil, &NotificationError{Method: "notifications/initialized", Err: err}
 	}
 
 	return &result, nil
 }
 
-// ListTools retrieves the list of available tools
+// ListTools retrieves the list of available tools, following pagination
 func (c *Client) ListTools() (*ListToolsResult, error) {
-	resp, err := c.doRequest("tools/list", map[string]interface{}{}, 2)
+	c.toolsChanged = false
+	result := &ListToolsResult{Tools: []Tool{}}
+	err := c.listPages("tools/list", 2, func(raw json.RawMessage) (string, error) {
+		var page ListToolsResult
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return "", fmt.Errorf("failed to parse tools list: %w", err)
+		}
+		result.Tools = append(result.Tools, page.Tools...)
+		result.Pages++
+		return page.NextCursor, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
+// Ping checks that the server is responsive
+func (c *Client) Ping() error {
+	resp, err := c.doRequest("ping", map[string]interface{}{}, 4)
+	if err != nil {
+		return err
+	}
 	if resp.Error != nil {
-		return nil, fmt.Errorf("server error: %s", resp.Error.Message)
+		return resp.Error
 	}
+	return nil
+}
 
-	var result ListToolsResult
-	if err := json.Unmarshal(resp.Result, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse tools list: %w", err)
+// SessionID returns the session id issued by the server, if any
+func (c *Client) SessionID() string {
+	return c.sessionID
+}
+
+// Terminate ends the session by sending DELETE with its session id. Servers
+// that don't allow clients to end sessions respond with 405.
+func (c *Client) Terminate() error {
+	if c.sessionID == "" || c.replayer != nil {
+		return nil
 	}
 
-	return &result, nil
+	httpReq, err := http.NewRequest("DELETE", c.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range c.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set("Mcp-Session-Id", c.sessionID)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkAuthStatus(resp); err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	c.sessionID = ""
+	return nil
 }
 
-// CallTool invokes a tool and returns the raw JSON result
+// CallTool invokes a tool and returns the raw JSON result. A JSON-RPC error
+// is returned as an *RPCError.
 func (c *Client) CallTool(name string, arguments json.RawMessage) (json.RawMessage, error) {
 	params := map[string]interface{}{
 		"name": name,
@@ -353,7 +588,10 @@ func (c *Client) CallTool(name string, arguments json.RawMessage) (json.RawMessa
 	if err != nil {
 		return nil, err
 	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
 
-	// Return raw result (including errors) as per user requirement
+	// Return raw result (including tool errors) as per user requirement
 	return resp.Result, nil
 }
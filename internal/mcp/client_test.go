@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 )
 
@@ -215,6 +216,7 @@ func TestStatelessServer_NoSessionHeader(t *testing.T) {
 
 func TestDoRequest_UnauthorizedError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer scope="read"`)
 		w.WriteHeader(http.StatusUnauthorized)
 		w.Write([]byte("authentication required"))
 	}))
@@ -235,6 +237,30 @@ func TestDoRequest_UnauthorizedError(t *testing.T) {
 	if unauthorizedErr.Body != "authentication required" {
 		t.Errorf("Body = %q, want %q", unauthorizedErr.Body, "authentication required")
 	}
+	if unauthorizedErr.WWWAuthenticate != `Bearer scope="read"` {
+		t.Errorf("WWWAuthenticate = %q, want %q", unauthorizedErr.WWWAuthenticate, `Bearer scope="read"`)
+	}
+}
+
+func TestDoRequest_ForbiddenError(t *testing.T) {
+	challenge := `Bearer error="insufficient_scope", scope="write"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", challenge)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("forbidden"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil)
+	_, err := client.Initialize()
+
+	var forbiddenErr *ForbiddenError
+	if !errors.As(err, &forbiddenErr) {
+		t.Fatalf("expected ForbiddenError, got %T: %v", err, err)
+	}
+	if forbiddenErr.WWWAuthenticate != challenge {
+		t.Errorf("WWWAuthenticate = %q, want %q", forbiddenErr.WWWAuthenticate, challenge)
+	}
 }
 
 func TestDoRequest_OtherError(t *testing.T) {
@@ -256,3 +282,206 @@ func TestDoRequest_OtherError(t *testing.T) {
 		t.Fatal("500 should not produce UnauthorizedError")
 	}
 }
+
+func TestListTools_FollowsPagination(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params struct {
+				Cursor string `json:"cursor"`
+			} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		cursors = append(cursors, req.Params.Cursor)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Params.Cursor {
+		case "":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"a"}],"nextCursor":"p2"}}`))
+		case "p2":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"b"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	result, err := NewClient(server.URL, nil).ListTools()
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(result.Tools) != 2 || result.Tools[0].Name != "a" || result.Tools[1].Name != "b" {
+		t.Errorf("unexpected tools %+v", result.Tools)
+	}
+	if len(cursors) != 2 || cursors[1] != "p2" {
+		t.Errorf("requested cursors %q, want [\"\" \"p2\"]", cursors)
+	}
+}
+
+func TestListTools_RepeatedCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":2,"result":{"tools":[],"nextCursor":"same"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, nil).ListTools()
+	var paginationErr *PaginationError
+	if !errors.As(err, &paginationErr) || paginationErr.Cursor != "same" {
+		t.Errorf("expected PaginationError for a repeated cursor, got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := decodeRPC(t, r)
+		w.Header().Set("Content-Type", "application/json")
+		if msg.Method != "ping" {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"method not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":4,"result":{}}`))
+	}))
+	defer server.Close()
+
+	if err := NewClient(server.URL, nil).Ping(); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+}
+
+func TestPing_RPCError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"method not found"}}`))
+	}))
+	defer server.Close()
+
+	err := NewClient(server.URL, nil).Ping()
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Errorf("expected RPCError -32601, got %v", err)
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			deleted = r.Header.Get("Mcp-Session-Id")
+			return
+		}
+		msg := decodeRPC(t, r)
+		if msg.Method == "initialize" {
+			w.Header().Set("Mcp-Session-Id", "sess-1")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","serverInfo":{"name":"s","version":"1"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil)
+	if _, err := client.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := client.Terminate(); err != nil {
+		t.Fatalf("Terminate failed: %v", err)
+	}
+	if deleted != "sess-1" {
+		t.Errorf("DELETE Mcp-Session-Id = %q, want sess-1", deleted)
+	}
+	if client.SessionID() != "" {
+		t.Errorf("session id not cleared after Terminate")
+	}
+}
+
+func TestClone_StartsNewSession(t *testing.T) {
+	var sessions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := decodeRPC(t, r)
+		if msg.Method == "initialize" {
+			if r.Header.Get("X-Test") != "yes" {
+				t.Errorf("clone lost headers")
+			}
+			id := "sess-" + strconv.Itoa(len(sessions)+1)
+			sessions = append(sessions, id)
+			w.Header().Set("Mcp-Session-Id", id)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","serverInfo":{"name":"s","version":"1"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, map[string]string{"X-Test": "yes"})
+	if _, err := client.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	clone := client.Clone()
+	if clone.SessionID() != "" {
+		t.Errorf("clone has session id %q before initialize", clone.SessionID())
+	}
+	if _, err := clone.Initialize(); err != nil {
+		t.Fatalf("clone Initialize failed: %v", err)
+	}
+	if client.SessionID() != "sess-1" || clone.SessionID() != "sess-2" {
+		t.Errorf("session ids = %q, %q; want sess-1, sess-2", client.SessionID(), clone.SessionID())
+	}
+}
+
+func TestCallTool_SSEToolsListChanged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := decodeRPC(t, r)
+		if msg.Method != "tools/call" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/tools/list_changed\"}\n\n"))
+		w.Write([]byte("event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":7,\"method\":\"ping\"}\n\n"))
+		w.Write([]byte("event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":3,\"result\":{\"content\":[]}}\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil)
+	result, err := client.CallTool("t", nil)
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if string(result) != `{"content":[]}` {
+		t.Errorf("result = %s, want the response after the notification", result)
+	}
+	if !client.ToolsChanged() {
+		t.Error("ToolsChanged() = false after notifications/tools/list_changed")
+	}
+}
+
+func TestCallTool_RPCError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"unknown tool"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, nil).CallTool("t", nil)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32602 {
+		t.Errorf("CallTool() error = %v, want RPCError -32602", err)
+	}
+}
+
+func TestCallTool_SSEWithoutNotification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"jsonrpc\":\"2.0\",\"id\":3,\"result\":{}}\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil)
+	if _, err := client.CallTool("t", nil); err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if client.ToolsChanged() {
+		t.Error("ToolsChanged() = true without a notification")
+	}
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Resource represents an MCP resource
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourcesResult is the result of a resources/list call
+type ListResourcesResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// PromptArgument describes an argument of a prompt template
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Prompt represents an MCP prompt template
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// ListPromptsResult is the result of a prompts/list call
+type ListPromptsResult struct {
+	Prompts    []Prompt `json:"prompts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// ListResources retrieves the list of available resources, following
+// pagination
+func (c *Client) ListResources() (*ListResourcesResult, error) {
+	result := &ListResourcesResult{Resources: []Resource{}}
+	err := c.listPages("resources/list", 5, func(raw json.RawMessage) (string, error) {
+		var page ListResourcesResult
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return "", fmt.Errorf("failed to parse resources list: %w", err)
+		}
+		result.Resources = append(result.Resources, page.Resources...)
+		return page.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListPrompts retrieves the list of available prompts, following pagination
+func (c *Client) ListPrompts() (*ListPromptsResult, error) {
+	result := &ListPromptsResult{Prompts: []Prompt{}}
+	err := c.listPages("prompts/list", 6, func(raw json.RawMessage) (string, error) {
+		var page ListPromptsResult
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return "", fmt.Errorf("failed to parse prompts list: %w", err)
+		}
+		result.Prompts = append(result.Prompts, page.Prompts...)
+		return page.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// maxListPages bounds the pagination of list methods against servers that
+// never stop returning a cursor.
+const maxListPages = 1000
+
+// PaginationError is returned when a list method's pagination never ends:
+// it repeats a cursor or exceeds maxListPages.
+type PaginationError struct {
+	Method string
+	// Cursor is the repeated cursor, if any.
+	Cursor string
+}
+
+func (e *PaginationError) Error() string {
+	if e.Cursor != "" {
+		return fmt.Sprintf("%s pagination repeated cursor %q", e.Method, e.Cursor)
+	}
+	return fmt.Sprintf("%s returned more than %d pages", e.Method, maxListPages)
+}
+
+// listPages requests every page of a paginated list method. addPage collects
+// the items of a page and returns its next cursor. Errors after the first
+// page name the page that failed.
+func (c *Client) listPages(method string, id int, addPage func(json.RawMessage) (string, error)) error {
+	seen := map[string]bool{}
+	cursor := ""
+	for page := 1; page <= maxListPages; page++ {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		resp, err := c.doRequest(method, params, id)
+		if err == nil && resp.Error != nil {
+			err = resp.Error
+		}
+		if err == nil {
+			cursor, err = addPage(resp.Result)
+		}
+		if err != nil {
+			if page > 1 {
+				return fmt.Errorf("%s page %d: %w", method, page, err)
+			}
+			return err
+		}
+		if cursor == "" {
+			return nil
+		}
+		if seen[cursor] {
+			return &PaginationError{Method: method, Cursor: cursor}
+		}
+		seen[cursor] = true
+	}
+	return &PaginationError{Method: method}
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListResources_FollowsPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		json.Unmarshal(body, &req)
+		if req.Method != "resources/list" {
+			t.Errorf("unexpected method %q", req.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.Params["cursor"] == "" {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":5,"result":{"resources":[{"uri":"file:///a","name":"a"}],"nextCursor":"p2"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":5,"result":{"resources":[{"uri":"file:///b","name":"b","mimeType":"text/plain"}]}}`))
+	}))
+	defer server.Close()
+
+	result, err := NewClient(server.URL, nil).ListResources()
+	if err != nil {
+		t.Fatalf("ListResources failed: %v", err)
+	}
+	if len(result.Resources) != 2 || result.Resources[1].URI != "file:///b" || result.Resources[1].MimeType != "text/plain" {
+		t.Errorf("resources = %+v", result.Resources)
+	}
+}
+
+func TestListPrompts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":6,"result":{"prompts":[{"name":"summarize","description":"Summarize","arguments":[{"name":"text","required":true}]}]}}`))
+	}))
+	defer server.Close()
+
+	result, err := NewClient(server.URL, nil).ListPrompts()
+	if err != nil {
+		t.Fatalf("ListPrompts failed: %v", err)
+	}
+	if len(result.Prompts) != 1 || result.Prompts[0].Name != "summarize" || !result.Prompts[0].Arguments[0].Required {
+		t.Errorf("prompts = %+v", result.Prompts)
+	}
+}
+
+func TestListPrompts_MethodNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":6,"error":{"code":-32601,"message":"method not found"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, nil).ListPrompts()
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Errorf("err = %v, want RPCError -32601", err)
+	}
+}
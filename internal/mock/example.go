@@ -0,0 +1,99 @@
+package mock
+
+import (
+	"encoding/json"
+
+	"github.com/juanibiapina/mcpli/internal/schema"
+)
+
+// maxExampleDepth is how many levels of nested objects and arrays an example
+// fills in; deeper values are null.
+const maxExampleDepth = 8
+
+// Example returns a value conforming to a JSON schema: its const, default,
+// first example or enum value, or a placeholder of its type. Objects include
+// all their properties and arrays hold one item.
+func Example(schema json.RawMessage) interface{} {
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil
+	}
+	return example(s, 0)
+}
+
+func example(s map[string]interface{}, depth int) interface{} {
+	if depth > maxExampleDepth {
+		return nil
+	}
+	if v, ok := s["const"]; ok {
+		return v
+	}
+	if v, ok := s["default"]; ok {
+		return v
+	}
+	if examples, ok := s["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if alternatives, ok := s[key].([]interface{}); ok && len(alternatives) > 0 {
+			if first, ok := alternatives[0].(map[string]interface{}); ok {
+				return example(first, depth+1)
+			}
+		}
+	}
+
+	switch schema.Type(s) {
+	case "object":
+		obj := map[string]interface{}{}
+		properties, _ := s["properties"].(map[string]interface{})
+		for name, prop := range properties {
+			if propSchema, ok := prop.(map[string]interface{}); ok {
+				obj[name] = example(propSchema, depth+1)
+			}
+		}
+		return obj
+	case "array":
+		items, _ := s["items"].(map[string]interface{})
+		if items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{example(items, depth+1)}
+	case "string":
+		return exampleString(s)
+	case "integer":
+		if min, ok := s["minimum"].(float64); ok {
+			return int64(min)
+		}
+		return 0
+	case "number":
+		if min, ok := s["minimum"].(float64); ok {
+			return min
+		}
+		return 0.0
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+func exampleString(s map[string]interface{}) string {
+	switch s["format"] {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	}
+	return "string"
+}
@@ -0,0 +1,376 @@
+// Package mock implements a mock MCP server that exposes tool definitions and
+// answers tool calls with canned or schema-generated results.
+package mock
+
+import (
+	"bufio"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/schema"
+)
+
+// ProtocolVersion is the MCP protocol version announced by the mock server.
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Spec describes the server to mock.
+type Spec struct {
+	ServerInfo config.ServerInfo `json:"server_info"`
+	Tools      []config.Tool     `json:"tools"`
+	// Responses maps tool names to canned tools/call results. Tools without
+	// one get a result generated from their schema.
+	Responses map[string]json.RawMessage `json:"responses,omitempty"`
+}
+
+// LoadSpec reads a spec file. A config server entry is a valid spec.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// Options configures the failures simulated by the mock server.
+type Options struct {
+	// Latency delays every response.
+	Latency time.Duration
+	// ErrorRate is the fraction of tool calls answered with a JSON-RPC error.
+	ErrorRate float64
+	// RequireToken makes HTTP requests without this bearer token fail with
+	// 401 Unauthorized.
+	RequireToken string
+	// UnauthorizedRate is the fraction of HTTP requests rejected with 401
+	// Unauthorized, as if the token had been revoked.
+	UnauthorizedRate float64
+	// SessionTTL expires HTTP sessions this long after initialize, after
+	// which requests fail with 404 Not Found. Zero means sessions never expire.
+	SessionTTL time.Duration
+	// SSE answers HTTP requests with a text/event-stream instead of JSON.
+	SSE bool
+	// PageSize paginates tools/list. Zero returns all tools at once.
+	PageSize int
+}
+
+// Server is a mock MCP server.
+type Server struct {
+	spec *Spec
+	opts Options
+
+	mu       sync.Mutex
+	sessions map[string]time.Time
+	log      io.Writer
+}
+
+// New returns a mock server for the spec.
+func New(spec *Spec, opts Options) *Server {
+	return &Server{spec: spec, opts: opts, sessions: make(map[string]time.Time), log: io.Discard}
+}
+
+// SetLog makes the server log one line per message to w.
+func (s *Server) SetLog(w io.Writer) {
+	s.log = w
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r *request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// ServeHTTP implements the streamable HTTP transport.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.sessions, r.Header.Get("Mcp-Session-Id"))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeHTTP(w, &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error"}})
+		return
+	}
+	fmt.Fprintf(s.log, "%s %s\n", time.Now().Format("15:04:05"), req.Method)
+
+	if req.Method == "initialize" {
+		id := newSessionID()
+		s.mu.Lock()
+		s.sessions[id] = time.Now()
+		s.mu.Unlock()
+		w.Header().Set("Mcp-Session-Id", id)
+	} else if status, msg := s.checkSession(r.Header.Get("Mcp-Session-Id")); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	resp := s.handle(&req)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	s.writeHTTP(w, resp)
+}
+
+// authorized applies the token requirement and the simulated 401s.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.opts.RequireToken != "" && r.Header.Get("Authorization") != "Bearer "+s.opts.RequireToken {
+		return false
+	}
+	return !chance(s.opts.UnauthorizedRate)
+}
+
+// checkSession returns the HTTP status for a request in a missing, unknown
+// or expired session, or 0 if the session is valid.
+func (s *Server) checkSession(id string) (int, string) {
+	if id == "" {
+		return http.StatusBadRequest, "missing Mcp-Session-Id"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created, ok := s.sessions[id]
+	if !ok {
+		return http.StatusNotFound, "unknown session"
+	}
+	if s.opts.SessionTTL > 0 && time.Since(created) > s.opts.SessionTTL {
+		delete(s.sessions, id)
+		return http.StatusNotFound, "session expired"
+	}
+	return 0, ""
+}
+
+func (s *Server) writeHTTP(w http.ResponseWriter, resp *response) {
+	body, _ := json.Marshal(resp)
+	if s.opts.SSE {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", body)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// ServeStdio implements the stdio transport: newline-delimited JSON-RPC
+// messages on r, responses on w. HTTP-only simulations don't apply.
+func (s *Server) ServeStdio(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	encoder := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			if err := encoder.Encode(&response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error"}}); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(s.log, "%s %s\n", time.Now().Format("15:04:05"), req.Method)
+
+		if resp := s.handle(&req); resp != nil {
+			if err := encoder.Encode(resp); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers a JSON-RPC message. Notifications return nil.
+func (s *Server) handle(req *request) *response {
+	if req.isNotification() {
+		return nil
+	}
+	if s.opts.Latency > 0 {
+		time.Sleep(s.opts.Latency)
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	var err *rpcError
+	switch req.Method {
+	case "":
+		err = &rpcError{Code: codeInvalidRequest, Message: "missing method"}
+	case "initialize":
+		resp.Result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      s.spec.ServerInfo,
+		}
+	case "ping":
+		resp.Result = map[string]interface{}{}
+	case "tools/list":
+		resp.Result, err = s.listTools(req.Params)
+	case "tools/call":
+		resp.Result, err = s.callTool(req.Params)
+	case "resources/list":
+		resp.Result = map[string]interface{}{"resources": []interface{}{}}
+	case "prompts/list":
+		resp.Result = map[string]interface{}{"prompts": []interface{}{}}
+	default:
+		err = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = err
+	}
+	return resp
+}
+
+// listTools returns the page of tools after the cursor, which is the index
+// of the first tool of the page.
+func (s *Server) listTools(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Cursor string `json:"cursor"`
+	}
+	if len(params) > 0 {
+		json.Unmarshal(params, &p)
+	}
+
+	tools := s.spec.Tools
+	if tools == nil {
+		tools = []config.Tool{}
+	}
+	result := map[string]interface{}{}
+	if s.opts.PageSize <= 0 {
+		result["tools"] = tools
+		return result, nil
+	}
+
+	start := 0
+	if p.Cursor != "" {
+		n, err := strconv.Atoi(p.Cursor)
+		if err != nil || n < 0 || n > len(tools) {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid cursor"}
+		}
+		start = n
+	}
+	end := min(start+s.opts.PageSize, len(tools))
+	result["tools"] = tools[start:end]
+	if end < len(tools) {
+		result["nextCursor"] = strconv.Itoa(end)
+	}
+	return result, nil
+}
+
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params"}
+	}
+
+	var tool *config.Tool
+	for i := range s.spec.Tools {
+		if s.spec.Tools[i].Name == p.Name {
+			tool = &s.spec.Tools[i]
+		}
+	}
+	if tool == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", p.Name)}
+	}
+
+	if chance(s.opts.ErrorRate) {
+		return nil, &rpcError{Code: codeInternalError, Message: "simulated error"}
+	}
+
+	if missing := schema.MissingRequired(tool.InputSchema, p.Arguments); len(missing) > 0 {
+		return textResult(fmt.Sprintf("missing required arguments: %s", strings.Join(missing, ", ")), true), nil
+	}
+
+	if canned, ok := s.spec.Responses[tool.Name]; ok {
+		return canned, nil
+	}
+
+	if len(tool.OutputSchema) > 0 {
+		structured := Example(tool.OutputSchema)
+		text, _ := json.Marshal(structured)
+		result := textResult(string(text), false)
+		result["structuredContent"] = structured
+		return result, nil
+	}
+
+	arguments, _ := json.Marshal(p.Arguments)
+	return textResult(fmt.Sprintf("mock result for %s with arguments %s", tool.Name, arguments), false), nil
+}
+
+func textResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []interface{}{map[string]interface{}{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// chance returns true with the given probability.
+func chance(p float64) bool {
+	return p > 0 && rand.Float64() < p
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,230 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+	"github.com/juanibiapina/mcpli/internal/mcp"
+)
+
+func testSpec() *Spec {
+	return &Spec{
+		ServerInfo: config.ServerInfo{Name: "mock", Version: "1.0"},
+		Tools: []config.Tool{
+			{
+				Name:        "search",
+				Description: "Search products",
+				InputSchema: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`),
+			},
+			{
+				Name:         "get_cart",
+				Description:  "Get the cart",
+				OutputSchema: json.RawMessage(`{"type":"object","properties":{"total":{"type":"number","minimum":1},"items":{"type":"array","items":{"type":"string"}}}}`),
+			},
+			{Name: "checkout", Description: "Checkout"},
+		},
+		Responses: map[string]json.RawMessage{
+			"checkout": json.RawMessage(`{"content":[{"type":"text","text":"order 42"}]}`),
+		},
+	}
+}
+
+func startMock(t *testing.T, opts Options) (*httptest.Server, *mcp.Client) {
+	t.Helper()
+	server := httptest.NewServer(New(testSpec(), opts))
+	t.Cleanup(server.Close)
+
+	client := mcp.NewClient(server.URL, nil)
+	if _, err := client.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return server, client
+}
+
+func TestServer_ListAndCallTools(t *testing.T) {
+	_, client := startMock(t, Options{})
+
+	tools, err := client.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(tools.Tools) != 3 || tools.Tools[0].Name != "search" {
+		t.Errorf("unexpected tools %+v", tools.Tools)
+	}
+
+	tests := []struct {
+		tool string
+		args string
+		want string
+	}{
+		{"search", `{"query":"milk"}`, `mock result for search with arguments {\"query\":\"milk\"}`},
+		{"search", `{}`, `"isError":true`},
+		{"get_cart", ``, `"structuredContent":{"items":["string"],"total":1}`},
+		{"checkout", ``, `order 42`},
+	}
+	for _, tt := range tests {
+		var args json.RawMessage
+		if tt.args != "" {
+			args = json.RawMessage(tt.args)
+		}
+		result, err := client.CallTool(tt.tool, args)
+		if err != nil {
+			t.Fatalf("CallTool(%s) failed: %v", tt.tool, err)
+		}
+		if !strings.Contains(string(result), tt.want) {
+			t.Errorf("CallTool(%s, %s) = %s, want it to contain %s", tt.tool, tt.args, result, tt.want)
+		}
+	}
+}
+
+func TestServer_SSE(t *testing.T) {
+	_, client := startMock(t, Options{SSE: true})
+
+	tools, err := client.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(tools.Tools) != 3 {
+		t.Errorf("expected 3 tools, got %d", len(tools.Tools))
+	}
+}
+
+func TestServer_ErrorRate(t *testing.T) {
+	_, client := startMock(t, Options{ErrorRate: 1})
+
+	_, err := client.CallTool("checkout", nil)
+	var rpcErr *mcp.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != codeInternalError {
+		t.Errorf("CallTool() error = %v, want a simulated internal error", err)
+	}
+}
+
+func TestServer_RequireToken(t *testing.T) {
+	server := httptest.NewServer(New(testSpec(), Options{RequireToken: "secret"}))
+	defer server.Close()
+
+	_, err := mcp.NewClient(server.URL, nil).Initialize()
+	var unauthorized *mcp.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedError, got %v", err)
+	}
+	if !strings.Contains(unauthorized.WWWAuthenticate, "invalid_token") {
+		t.Errorf("WWW-Authenticate = %q", unauthorized.WWWAuthenticate)
+	}
+
+	client := mcp.NewClient(server.URL, map[string]string{"Authorization": "Bearer secret"})
+	if _, err := client.Initialize(); err != nil {
+		t.Errorf("Initialize with token failed: %v", err)
+	}
+}
+
+func TestServer_SessionExpiry(t *testing.T) {
+	_, client := startMock(t, Options{SessionTTL: 50 * time.Millisecond})
+
+	if _, err := client.ListTools(); err != nil {
+		t.Fatalf("ListTools before expiry failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.ListTools(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected 404 after session expiry, got %v", err)
+	}
+}
+
+func TestServer_RejectsMissingSession(t *testing.T) {
+	server := httptest.NewServer(New(testSpec(), Options{}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	s := New(testSpec(), Options{PageSize: 2})
+
+	var names []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		params, _ := json.Marshal(map[string]string{"cursor": cursor})
+		resp := s.handle(&request{ID: json.RawMessage("1"), Method: "tools/list", Params: params})
+		if resp.Error != nil {
+			t.Fatalf("tools/list failed: %s", resp.Error.Message)
+		}
+		result := resp.Result.(map[string]interface{})
+		for _, tool := range result["tools"].([]config.Tool) {
+			names = append(names, tool.Name)
+		}
+		next, ok := result["nextCursor"].(string)
+		if !ok {
+			break
+		}
+		cursor = next
+	}
+
+	if want := []string{"search", "get_cart", "checkout"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("paginated tools = %v, want %v", names, want)
+	}
+}
+
+func TestServer_Stdio(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+	}, "\n"))
+	var out bytes.Buffer
+
+	if err := New(testSpec(), Options{}).ServeStdio(in, &out); err != nil {
+		t.Fatalf("ServeStdio failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 responses, got %d:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], `"serverInfo":{"name":"mock","version":"1.0"}`) {
+		t.Errorf("unexpected initialize response %s", lines[0])
+	}
+	if lines[1] != `{"jsonrpc":"2.0","id":2,"result":{}}` {
+		t.Errorf("unexpected ping response %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"code":-32601`) {
+		t.Errorf("unexpected response for unknown method %s", lines[2])
+	}
+}
+
+func TestExample(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`{"type":"string"}`, `"string"`},
+		{`{"type":"string","format":"email"}`, `"user@example.com"`},
+		{`{"type":"integer","minimum":3}`, `3`},
+		{`{"type":["null","boolean"]}`, `false`},
+		{`{"enum":["a","b"]}`, `"a"`},
+		{`{"type":"string","default":"x"}`, `"x"`},
+		{`{"anyOf":[{"type":"number"},{"type":"string"}]}`, `0`},
+		{`{"properties":{"tags":{"type":"array","items":{"type":"string"}}}}`, `{"tags":["string"]}`},
+	}
+	for _, tt := range tests {
+		got, _ := json.Marshal(Example(json.RawMessage(tt.schema)))
+		if string(got) != tt.want {
+			t.Errorf("Example(%s) = %s, want %s", tt.schema, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package oauth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"filippo.io/age"
+	"github.com/adrg/xdg"
+	"github.com/zalando/go-keyring"
+)
+
+// Auth store backend names, as selected by the auth_store config setting.
+const (
+	BackendFile    = "file"
+	BackendKeyring = "keyring"
+	BackendAge     = "age"
+)
+
+// keyringService and keyringUser identify the auth store in the OS secret service.
+const (
+	keyringService = "mcpli"
+	keyringUser    = "auth"
+)
+
+// keyringChunkSize bounds the size of a single keyring secret. The Windows
+// Credential Manager rejects secrets over 2560 bytes and the macOS keychain
+// command line over 4096, so larger stores are split into several secrets.
+const keyringChunkSize = 2000
+
+// keyringChunksPrefix marks a keyring secret that holds the number of chunks
+// the auth store is split into, stored as keyringUser.1, keyringUser.2, ...
+const keyringChunksPrefix = "mcpli-chunks:"
+
+// Backend persists the serialized auth store.
+type Backend interface {
+	// Name returns the backend name used in the config.
+	Name() string
+	// Load returns the stored data, or nil if nothing has been stored yet.
+	Load() ([]byte, error)
+	// Save replaces the stored data.
+	Save(data []byte) error
+	// Delete removes the stored data. Deleting missing data is not an error.
+	Delete() error
+}
+
+// PassphraseFunc returns the passphrase of the encrypted auth store. confirm
+// is true when a new store is created, so the passphrase should be entered twice.
+type PassphraseFunc func(confirm bool) (string, error)
+
+// defaultBackend is the backend used by LoadStore.
+var defaultBackend Backend = FileBackend{}
+
+// SetBackend selects the backend used by LoadStore.
+func SetBackend(b Backend) {
+	defaultBackend = b
+}
+
+// NewBackend returns the backend with the given name. An empty name selects
+// the plaintext file. passphrase is only used by the age backend.
+func NewBackend(name string, passphrase PassphraseFunc) (Backend, error) {
+	switch name {
+	case "", BackendFile:
+		return FileBackend{}, nil
+	case BackendKeyring:
+		return KeyringBackend{}, nil
+	case BackendAge:
+		return &AgeBackend{Passphrase: passphrase}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth store %q (expected %s, %s or %s)", name, BackendFile, BackendKeyring, BackendAge)
+	}
+}
+
+// FileBackend stores the auth store as plaintext JSON readable only by the user.
+type FileBackend struct{}
+
+func (FileBackend) Name() string { return BackendFile }
+
+func (FileBackend) Load() ([]byte, error) {
+	return readFile(storePath())
+}
+
+func (FileBackend) Save(data []byte) error {
+	return writeFile(storePath(), data)
+}
+
+func (FileBackend) Delete() error {
+	return removeFile(storePath())
+}
+
+// KeyringBackend stores the auth store in the OS secret service: the Secret
+// Service API over D-Bus on Linux, the Keychain on macOS and the Credential
+// Manager on Windows. Stores larger than keyringChunkSize are split into
+// several secrets.
+type KeyringBackend struct{}
+
+func (KeyringBackend) Name() string { return BackendKeyring }
+
+func (KeyringBackend) Load() ([]byte, error) {
+	secret, err := keyring.Get(keyringService, keyringUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from the OS keyring: %w", err)
+	}
+
+	n, chunked := keyringChunks(secret)
+	if !chunked {
+		return []byte(secret), nil
+	}
+	var data strings.Builder
+	for i := 1; i <= n; i++ {
+		chunk, err := keyring.Get(keyringService, keyringChunkUser(i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part %d of %d from the OS keyring: %w", i, n, err)
+		}
+		data.WriteString(chunk)
+	}
+	return []byte(data.String()), nil
+}
+
+func (KeyringBackend) Save(data []byte) error {
+	old, _ := keyring.Get(keyringService, keyringUser)
+	oldChunks, _ := keyringChunks(old)
+
+	chunks := splitChunks(string(data), keyringChunkSize)
+	secret := string(data)
+	if len(chunks) > 1 {
+		for i, chunk := range chunks {
+			if err := keyring.Set(keyringService, keyringChunkUser(i+1), chunk); err != nil {
+				return fmt.Errorf("failed to write to the OS keyring: %w", err)
+			}
+		}
+		secret = keyringChunksPrefix + strconv.Itoa(len(chunks))
+	} else {
+		chunks = nil
+	}
+	if err := keyring.Set(keyringService, keyringUser, secret); err != nil {
+		return fmt.Errorf("failed to write to the OS keyring: %w", err)
+	}
+
+	// Remove the chunks of a larger previous store
+	for i := len(chunks) + 1; i <= oldChunks; i++ {
+		if err := deleteKeyringSecret(keyringChunkUser(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (KeyringBackend) Delete() error {
+	secret, _ := keyring.Get(keyringService, keyringUser)
+	n, _ := keyringChunks(secret)
+	for i := 1; i <= n; i++ {
+		if err := deleteKeyringSecret(keyringChunkUser(i)); err != nil {
+			return err
+		}
+	}
+	return deleteKeyringSecret(keyringUser)
+}
+
+// keyringChunks returns the number of chunks announced by the main keyring
+// secret, and whether the store is chunked at all.
+func keyringChunks(secret string) (int, bool) {
+	rest, ok := strings.CutPrefix(secret, keyringChunksPrefix)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	return n, err == nil
+}
+
+func keyringChunkUser(i int) string {
+	return keyringUser + "." + strconv.Itoa(i)
+}
+
+// splitChunks splits s into chunks of at most size bytes without splitting
+// UTF-8 sequences, since the secret service only stores valid strings.
+func splitChunks(s string, size int) []string {
+	var chunks []string
+	for len(s) > size {
+		end := size
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		chunks = append(chunks, s[:end])
+		s = s[end:]
+	}
+	return append(chunks, s)
+}
+
+func deleteKeyringSecret(user string) error {
+	err := keyring.Delete(keyringService, user)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete from the OS keyring: %w", err)
+	}
+	return nil
+}
+
+// AgeBackend stores the auth store in a file encrypted with a passphrase
+// using age (scrypt recipient).
+type AgeBackend struct {
+	Passphrase PassphraseFunc
+
+	// workFactor overrides the scrypt work factor; tests lower it.
+	workFactor int
+	passphrase string
+}
+
+func (b *AgeBackend) Name() string { return BackendAge }
+
+// agePath returns the path to the encrypted auth store file.
+func agePath() string {
+	return filepath.Join(xdg.StateHome, "mcpli", "auth.json.age")
+}
+
+func (b *AgeBackend) Load() ([]byte, error) {
+	ciphertext, err := readFile(agePath())
+	if err != nil || ciphertext == nil {
+		return nil, err
+	}
+
+	passphrase, err := b.getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identity)
+	if err != nil {
+		// Forget a wrong passphrase so the next attempt asks again
+		b.passphrase = ""
+		return nil, fmt.Errorf("failed to decrypt auth store: %w", err)
+	}
+	return io.ReadAll(r)
+}
+
+func (b *AgeBackend) Save(data []byte) error {
+	_, statErr := os.Stat(agePath())
+	passphrase, err := b.getPassphrase(os.IsNotExist(statErr))
+	if err != nil {
+		return err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+	if b.workFactor > 0 {
+		recipient.SetWorkFactor(b.workFactor)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt auth store: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to encrypt auth store: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt auth store: %w", err)
+	}
+
+	return writeFile(agePath(), buf.Bytes())
+}
+
+func (b *AgeBackend) Delete() error {
+	return removeFile(agePath())
+}
+
+// getPassphrase asks for the passphrase once and remembers it, so loading and
+// saving the store in the same command prompts only once.
+func (b *AgeBackend) getPassphrase(confirm bool) (string, error) {
+	if b.passphrase != "" {
+		return b.passphrase, nil
+	}
+	if b.Passphrase == nil {
+		return "", fmt.Errorf("auth store is encrypted but no passphrase is available")
+	}
+	passphrase, err := b.Passphrase(confirm)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("auth store passphrase must not be empty")
+	}
+	b.passphrase = passphrase
+	return passphrase, nil
+}
+
+// Migrate moves the auth store from one backend to another and returns the
+// number of entries moved. Entries already stored in the new backend are
+// kept; migration is refused if both backends hold credentials under the
+// same key. The steps are ordered so that a failure never loses credentials:
+// the merged store is saved to the new backend, then switchBackend records
+// the new backend (e.g. in the config), and only then is the old backend
+// deleted. If switchBackend fails, the copy is removed again.
+func Migrate(from, to Backend, switchBackend func() error) (int, error) {
+	store, err := loadStoreFrom(from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load auth store from %s: %w", from.Name(), err)
+	}
+	target, err := loadStoreFrom(to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load auth store from %s: %w", to.Name(), err)
+	}
+
+	var conflicts []string
+	for key := range store.Entries {
+		if _, exists := target.Entries[key]; exists {
+			conflicts = append(conflicts, key)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return 0, fmt.Errorf("%s already holds credentials for %s; log out there first or migrate them back", to.Name(), strings.Join(conflicts, ", "))
+	}
+
+	for key, entry := range store.Entries {
+		target.Entries[key] = entry
+	}
+	for key, meta := range store.Metadata {
+		if target.Metadata == nil {
+			target.Metadata = make(map[string]*CachedMetadata)
+		}
+		target.Metadata[key] = meta
+	}
+	if err := target.Save(); err != nil {
+		return 0, fmt.Errorf("failed to save auth store to %s: %w", to.Name(), err)
+	}
+
+	if err := switchBackend(); err != nil {
+		// Undo the copy so that migrating again doesn't conflict with it
+		for key := range store.Entries {
+			delete(target.Entries, key)
+		}
+		if undoErr := target.Save(); undoErr != nil {
+			return 0, fmt.Errorf("%w (and failed to remove the copy from %s: %v)", err, to.Name(), undoErr)
+		}
+		return 0, err
+	}
+
+	if err := from.Delete(); err != nil {
+		return 0, fmt.Errorf("auth store moved to %s but not removed from %s: %w", to.Name(), from.Name(), err)
+	}
+
+	return len(store.Entries), nil
+}
+
+// readFile reads a file, returning nil data if it doesn't exist.
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// writeFile writes a file with 0600 permissions, creating its directory.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// removeFile removes a file, ignoring a missing file.
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package oauth
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/zalando/go-keyring"
+)
+
+func testAgeBackend(passphrase string) *AgeBackend {
+	return &AgeBackend{
+		Passphrase: func(bool) (string, error) { return passphrase, nil },
+		workFactor: 10,
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", BackendFile},
+		{"file", BackendFile},
+		{"keyring", BackendKeyring},
+		{"age", BackendAge},
+	}
+
+	for _, tt := range tests {
+		b, err := NewBackend(tt.name, nil)
+		if err != nil {
+			t.Fatalf("NewBackend(%q) error: %v", tt.name, err)
+		}
+		if b.Name() != tt.want {
+			t.Errorf("NewBackend(%q).Name() = %q, want %q", tt.name, b.Name(), tt.want)
+		}
+	}
+
+	if _, err := NewBackend("vault", nil); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestAgeBackend_RoundTrip(t *testing.T) {
+	setTestStateHome(t)
+
+	b := testAgeBackend("secret")
+	if err := b.Save([]byte(`{"entries":{}}`)); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	ciphertext, err := os.ReadFile(agePath())
+	if err != nil {
+		t.Fatalf("failed to read encrypted file: %v", err)
+	}
+	if bytes.Contains(ciphertext, []byte("entries")) {
+		t.Error("encrypted file contains plaintext")
+	}
+
+	data, err := testAgeBackend("secret").Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if string(data) != `{"entries":{}}` {
+		t.Errorf("Load() = %q", data)
+	}
+
+	if _, err := testAgeBackend("wrong").Load(); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+}
+
+func TestAgeBackend_ConfirmsNewStore(t *testing.T) {
+	setTestStateHome(t)
+
+	var confirms []bool
+	b := &AgeBackend{
+		Passphrase: func(confirm bool) (string, error) {
+			confirms = append(confirms, confirm)
+			return "secret", nil
+		},
+		workFactor: 10,
+	}
+
+	if err := b.Save([]byte("{}")); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := b.Save([]byte("{}")); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	// The passphrase is asked once, with confirmation since the file was new
+	if len(confirms) != 1 || !confirms[0] {
+		t.Errorf("passphrase prompts = %v, want [true]", confirms)
+	}
+}
+
+func TestAgeBackend_LoadMissing(t *testing.T) {
+	setTestStateHome(t)
+
+	b := &AgeBackend{
+		Passphrase: func(bool) (string, error) {
+			t.Fatal("passphrase should not be requested without a store")
+			return "", nil
+		},
+	}
+	data, err := b.Load()
+	if err != nil || data != nil {
+		t.Errorf("Load() = %q, %v; want nil, nil", data, err)
+	}
+}
+
+func TestKeyringBackend_RoundTrip(t *testing.T) {
+	keyring.MockInit()
+
+	b := KeyringBackend{}
+	data, err := b.Load()
+	if err != nil || data != nil {
+		t.Fatalf("Load() = %q, %v; want nil, nil", data, err)
+	}
+
+	if err := b.Save([]byte(`{"entries":{}}`)); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	data, err = b.Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if string(data) != `{"entries":{}}` {
+		t.Errorf("Load() = %q", data)
+	}
+
+	if err := b.Delete(); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := b.Delete(); err != nil {
+		t.Errorf("Delete() of missing data error: %v", err)
+	}
+}
+
+func TestKeyringBackend_Chunks(t *testing.T) {
+	keyring.MockInit()
+
+	b := KeyringBackend{}
+	large := `{"entries":{"x":"` + strings.Repeat("é", keyringChunkSize) + `"}}`
+	if err := b.Save([]byte(large)); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	main, _ := keyring.Get(keyringService, keyringUser)
+	if main != keyringChunksPrefix+"3" {
+		t.Errorf("main secret = %q, want 3 chunks", main)
+	}
+	for i := 1; i <= 3; i++ {
+		chunk, err := keyring.Get(keyringService, keyringChunkUser(i))
+		if err != nil || len(chunk) > keyringChunkSize || !utf8.ValidString(chunk) {
+			t.Errorf("chunk %d = %d bytes, %v", i, len(chunk), err)
+		}
+	}
+	data, err := b.Load()
+	if err != nil || string(data) != large {
+		t.Fatalf("Load() = %d bytes, %v; want the saved store", len(data), err)
+	}
+
+	// A smaller store removes the chunks of the larger one
+	if err := b.Save([]byte(`{"entries":{}}`)); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if _, err := keyring.Get(keyringService, keyringChunkUser(1)); err != keyring.ErrNotFound {
+		t.Errorf("chunk 1 still stored: %v", err)
+	}
+	if data, _ := b.Load(); string(data) != `{"entries":{}}` {
+		t.Errorf("Load() = %q", data)
+	}
+
+	if err := b.Save([]byte(large)); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := b.Delete(); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := keyring.Get(keyringService, keyringChunkUser(2)); err != keyring.ErrNotFound {
+		t.Errorf("chunk 2 still stored after Delete: %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	setTestStateHome(t)
+
+	store := &AuthStore{
+		Entries: map[string]*AuthEntry{
+			"https://example.com/mcp": {ClientID: "client", AccessToken: "token"},
+		},
+		backend: FileBackend{},
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	to := testAgeBackend("secret")
+	switched := false
+	n, err := Migrate(FileBackend{}, to, func() error {
+		if _, err := os.Stat(storePath()); err != nil {
+			t.Error("plaintext store removed before the switch was recorded")
+		}
+		switched = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	if n != 1 || !switched {
+		t.Errorf("Migrate() moved %d entries, switched = %v; want 1, true", n, switched)
+	}
+
+	if _, err := os.Stat(storePath()); !os.IsNotExist(err) {
+		t.Error("plaintext store was not removed")
+	}
+
+	migrated, err := loadStoreFrom(testAgeBackend("secret"))
+	if err != nil {
+		t.Fatalf("loadStoreFrom() error: %v", err)
+	}
+	entry := migrated.Entries["https://example.com/mcp"]
+	if entry == nil || entry.AccessToken != "token" {
+		t.Errorf("migrated entry = %+v", entry)
+	}
+
+	// Saving the migrated store writes back to the encrypted backend
+	migrated.Entries["https://other.example.com/mcp"] = &AuthEntry{ClientID: "other"}
+	if err := migrated.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if _, err := os.Stat(storePath()); !os.IsNotExist(err) {
+		t.Error("save after migration wrote the plaintext store")
+	}
+}
+
+func TestMigrate_KeepsTargetEntries(t *testing.T) {
+	setTestStateHome(t)
+	keyring.MockInit()
+
+	source := &AuthStore{Entries: map[string]*AuthEntry{"https://a.example.com/mcp": {AccessToken: "a"}}, backend: FileBackend{}}
+	if err := source.Save(); err != nil {
+		t.Fatal(err)
+	}
+	target := &AuthStore{Entries: map[string]*AuthEntry{"https://b.example.com/mcp": {AccessToken: "b"}}, backend: KeyringBackend{}}
+	if err := target.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed switch leaves both stores as they were
+	if _, err := Migrate(FileBackend{}, KeyringBackend{}, func() error { return errors.New("config is read-only") }); err == nil {
+		t.Fatal("Migrate() succeeded despite the failed switch")
+	}
+	if _, err := os.Stat(storePath()); err != nil {
+		t.Error("plaintext store removed after a failed switch")
+	}
+	if kept, _ := loadStoreFrom(KeyringBackend{}); len(kept.Entries) != 1 {
+		t.Errorf("keyring entries after a failed switch = %v, want only the original", kept.Entries)
+	}
+
+	if _, err := Migrate(FileBackend{}, KeyringBackend{}, func() error { return nil }); err != nil {
+		t.Fatalf("Migrate() error: %v", err)
+	}
+	merged, err := loadStoreFrom(KeyringBackend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Entries) != 2 {
+		t.Errorf("merged entries = %v, want both", merged.Entries)
+	}
+
+	// Credentials under the same key in both stores are never overwritten
+	if err := source.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(FileBackend{}, KeyringBackend{}, func() error { return nil }); err == nil || !strings.Contains(err.Error(), "https://a.example.com/mcp") {
+		t.Errorf("Migrate() error = %v, want a conflict", err)
+	}
+}
+
+func TestLoadStore_UsesConfiguredBackend(t *testing.T) {
+	setTestStateHome(t)
+	keyring.MockInit()
+
+	SetBackend(KeyringBackend{})
+	t.Cleanup(func() { SetBackend(FileBackend{}) })
+
+	store, err := LoadStore()
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	store.Entries["https://example.com/mcp"] = &AuthEntry{ClientID: "client"}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	if _, err := os.Stat(storePath()); !os.IsNotExist(err) {
+		t.Error("keyring backend wrote the plaintext store")
+	}
+
+	reloaded, err := LoadStore()
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	if reloaded.Entries["https://example.com/mcp"] == nil {
+		t.Error("entry not found after reload")
+	}
+}
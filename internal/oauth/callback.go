@@ -6,16 +6,82 @@ import (
 	"html"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 const (
+	// CallbackPort is the fixed callback port used by earlier versions of
+	// mcpli. Clients registered with its redirect URI keep working by
+	// passing it as the redirect port.
 	CallbackPort = 19877
 	CallbackPath = "/oauth/callback"
 )
 
-// RedirectURI returns the fixed redirect URI for the OAuth callback.
-func RedirectURI() string {
-	return fmt.Sprintf("http://127.0.0.1:%d%s", CallbackPort, CallbackPath)
+// CallbackConfig selects the loopback address the callback server listens on.
+type CallbackConfig struct {
+	// Host is the loopback IP address to bind: 127.0.0.1 (default) or ::1.
+	Host string
+	// PortMin and PortMax bound the port. Zero binds an ephemeral port and
+	// equal values require that exact port.
+	PortMin int
+	PortMax int
+	// Preferred is a port tried first when it lies within the bounds (or any
+	// port is allowed), so an existing registration can be reused.
+	Preferred int
+}
+
+// ParsePortRange parses a port range like "50000-50100" or a single port.
+func ParsePortRange(s string) (min, max int, err error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	min, err = strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	max = min
+	if isRange {
+		max, err = strconv.Atoi(strings.TrimSpace(hi))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid port range %q", s)
+		}
+	}
+	if min < 1 || max > 65535 || min > max {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	return min, max, nil
+}
+
+// listen binds the callback listener according to the config.
+func (c CallbackConfig) listen() (net.Listener, error) {
+	host := c.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return nil, fmt.Errorf("callback host %q is not a loopback IP address", host)
+	}
+
+	var candidates []int
+	if c.Preferred != 0 && (c.PortMin == 0 || (c.Preferred >= c.PortMin && c.Preferred <= c.PortMax)) {
+		candidates = append(candidates, c.Preferred)
+	}
+	if c.PortMin == 0 {
+		candidates = append(candidates, 0)
+	} else {
+		for port := c.PortMin; port <= c.PortMax; port++ {
+			candidates = append(candidates, port)
+		}
+	}
+
+	var lastErr error
+	for _, port := range candidates {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			return listener, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
 }
 
 // callbackResult holds the authorization code or error from the callback.
@@ -27,14 +93,16 @@ type callbackResult struct {
 
 // CallbackServer is a temporary HTTP server that receives the OAuth callback.
 type CallbackServer struct {
-	server   *http.Server
-	resultCh chan callbackResult
+	server      *http.Server
+	resultCh    chan callbackResult
+	redirectURI string
 }
 
-// StartCallbackServer starts the callback server and begins listening.
+// StartCallbackServer starts the callback server on a loopback address
+// selected by cfg and begins listening.
 // The server must be started before opening the browser to avoid a race condition
 // where the OAuth redirect arrives before the server is ready.
-func StartCallbackServer() (*CallbackServer, error) {
+func StartCallbackServer(cfg CallbackConfig) (*CallbackServer, error) {
 	resultCh := make(chan callbackResult, 1)
 
 	mux := http.NewServeMux()
@@ -62,10 +130,9 @@ func StartCallbackServer() (*CallbackServer, error) {
 		fmt.Fprint(w, "<html><body><h2>Authentication Successful</h2><p>You can close this window and return to the terminal.</p></body></html>")
 	})
 
-	addr := fmt.Sprintf("127.0.0.1:%d", CallbackPort)
-	listener, err := net.Listen("tcp", addr)
+	listener, err := cfg.listen()
 	if err != nil {
-		return nil, fmt.Errorf("failed to start callback server on %s: %w", addr, err)
+		return nil, fmt.Errorf("failed to start callback server: %w", err)
 	}
 
 	server := &http.Server{Handler: mux}
@@ -73,7 +140,18 @@ func StartCallbackServer() (*CallbackServer, error) {
 		_ = server.Serve(listener)
 	}()
 
-	return &CallbackServer{server: server, resultCh: resultCh}, nil
+	redirectURI := fmt.Sprintf("http://%s%s", listener.Addr().String(), CallbackPath)
+	return &CallbackServer{server: server, resultCh: resultCh, redirectURI: redirectURI}, nil
+}
+
+// RedirectURI returns the redirect URI served by the callback server.
+func (cs *CallbackServer) RedirectURI() string {
+	return cs.redirectURI
+}
+
+// Close stops the callback server without waiting for a callback.
+func (cs *CallbackServer) Close() {
+	_ = cs.server.Shutdown(context.Background())
 }
 
 // Wait blocks until the callback is received or the context is cancelled.
@@ -3,20 +3,22 @@ package oauth
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestCallbackServer_CodeReceived(t *testing.T) {
-	cs, err := StartCallbackServer()
+	cs, err := StartCallbackServer(CallbackConfig{})
 	if err != nil {
-		t.Fatalf("StartCallbackServer() error: %v", err)
+		t.Fatalf("StartCallbackServer(CallbackConfig{}) error: %v", err)
 	}
 
 	// Send the callback in a goroutine
 	go func() {
-		url := fmt.Sprintf("http://127.0.0.1:%d%s?code=test-code&state=test-state", CallbackPort, CallbackPath)
+		url := cs.RedirectURI() + "?code=test-code&state=test-state"
 		resp, err := http.Get(url)
 		if err != nil {
 			t.Errorf("callback request failed: %v", err)
@@ -41,13 +43,13 @@ func TestCallbackServer_CodeReceived(t *testing.T) {
 }
 
 func TestCallbackServer_ErrorParam(t *testing.T) {
-	cs, err := StartCallbackServer()
+	cs, err := StartCallbackServer(CallbackConfig{})
 	if err != nil {
-		t.Fatalf("StartCallbackServer() error: %v", err)
+		t.Fatalf("StartCallbackServer(CallbackConfig{}) error: %v", err)
 	}
 
 	go func() {
-		url := fmt.Sprintf("http://127.0.0.1:%d%s?error=access_denied&error_description=nope", CallbackPort, CallbackPath)
+		url := cs.RedirectURI() + "?error=access_denied&error_description=nope"
 		resp, err := http.Get(url)
 		if err != nil {
 			t.Errorf("callback request failed: %v", err)
@@ -66,13 +68,13 @@ func TestCallbackServer_ErrorParam(t *testing.T) {
 }
 
 func TestCallbackServer_MissingCode(t *testing.T) {
-	cs, err := StartCallbackServer()
+	cs, err := StartCallbackServer(CallbackConfig{})
 	if err != nil {
-		t.Fatalf("StartCallbackServer() error: %v", err)
+		t.Fatalf("StartCallbackServer(CallbackConfig{}) error: %v", err)
 	}
 
 	go func() {
-		url := fmt.Sprintf("http://127.0.0.1:%d%s", CallbackPort, CallbackPath)
+		url := cs.RedirectURI()
 		resp, err := http.Get(url)
 		if err != nil {
 			t.Errorf("callback request failed: %v", err)
@@ -89,3 +91,101 @@ func TestCallbackServer_MissingCode(t *testing.T) {
 		t.Fatal("expected error for missing code, got nil")
 	}
 }
+
+func TestCallbackServer_EphemeralPorts(t *testing.T) {
+	// Two concurrent logins must not collide
+	cs1, err := StartCallbackServer(CallbackConfig{})
+	if err != nil {
+		t.Fatalf("StartCallbackServer() error: %v", err)
+	}
+	defer cs1.Close()
+
+	cs2, err := StartCallbackServer(CallbackConfig{})
+	if err != nil {
+		t.Fatalf("second StartCallbackServer() error: %v", err)
+	}
+	defer cs2.Close()
+
+	if cs1.RedirectURI() == cs2.RedirectURI() {
+		t.Errorf("both servers use redirect URI %s", cs1.RedirectURI())
+	}
+	if !strings.HasPrefix(cs1.RedirectURI(), "http://127.0.0.1:") || !strings.HasSuffix(cs1.RedirectURI(), CallbackPath) {
+		t.Errorf("RedirectURI() = %q, want loopback callback URI", cs1.RedirectURI())
+	}
+}
+
+func TestCallbackServer_PortRange(t *testing.T) {
+	// Occupy a port and offer a range containing it and the next one
+	blocker, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	defer blocker.Close()
+	port := blocker.Addr().(*net.TCPAddr).Port
+
+	cs, err := StartCallbackServer(CallbackConfig{PortMin: port, PortMax: port + 1})
+	if err != nil {
+		t.Skipf("next port unavailable: %v", err)
+	}
+	defer cs.Close()
+
+	want := fmt.Sprintf("http://127.0.0.1:%d%s", port+1, CallbackPath)
+	if cs.RedirectURI() != want {
+		t.Errorf("RedirectURI() = %q, want %q", cs.RedirectURI(), want)
+	}
+}
+
+func TestCallbackServer_FixedPortInUse(t *testing.T) {
+	blocker, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	defer blocker.Close()
+	port := blocker.Addr().(*net.TCPAddr).Port
+
+	if _, err := StartCallbackServer(CallbackConfig{PortMin: port, PortMax: port}); err == nil {
+		t.Fatal("expected error when the fixed port is taken")
+	}
+}
+
+func TestCallbackServer_IPv6(t *testing.T) {
+	cs, err := StartCallbackServer(CallbackConfig{Host: "::1"})
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	defer cs.Close()
+
+	if !strings.HasPrefix(cs.RedirectURI(), "http://[::1]:") {
+		t.Errorf("RedirectURI() = %q, want IPv6 loopback URI", cs.RedirectURI())
+	}
+}
+
+func TestCallbackServer_RejectsNonLoopback(t *testing.T) {
+	if _, err := StartCallbackServer(CallbackConfig{Host: "0.0.0.0"}); err == nil {
+		t.Fatal("expected error for non-loopback host")
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		min, max int
+		wantErr  bool
+	}{
+		{"8765", 8765, 8765, false},
+		{"50000-50100", 50000, 50100, false},
+		{"50100-50000", 0, 0, true},
+		{"0-10", 0, 0, true},
+		{"abc", 0, 0, true},
+	}
+	for _, tt := range tests {
+		min, max, err := ParsePortRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePortRange(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if min != tt.min || max != tt.max {
+			t.Errorf("ParsePortRange(%q) = %d, %d, want %d, %d", tt.in, min, max, tt.min, tt.max)
+		}
+	}
+}
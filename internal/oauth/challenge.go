@@ -0,0 +1,100 @@
+package oauth
+
+import (
+	"strings"
+)
+
+// Challenge holds the parameters of a Bearer WWW-Authenticate challenge
+// (RFC 6750, RFC 9728).
+type Challenge struct {
+	Error            string
+	Scope            string
+	ResourceMetadata string
+}
+
+// InsufficientScope returns true if the challenge asks for additional scopes.
+func (c Challenge) InsufficientScope() bool {
+	return c.Error == "insufficient_scope"
+}
+
+// Scopes returns the scopes requested by the challenge.
+func (c Challenge) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// ParseChallenge extracts the Bearer challenge parameters from a
+// WWW-Authenticate header value. Unknown schemes and parameters are ignored.
+func ParseChallenge(header string) Challenge {
+	var c Challenge
+	inBearer := false
+
+	s := strings.TrimSpace(header)
+	for s != "" {
+		var token string
+		token, s = readToken(s)
+		s = strings.TrimLeft(s, " \t")
+
+		if token == "" {
+			// Skip a stray separator
+			s = strings.TrimLeft(s[1:], " \t,")
+			continue
+		}
+
+		if !strings.HasPrefix(s, "=") {
+			// A bare token starts a new challenge
+			inBearer = strings.EqualFold(token, "Bearer")
+			s = strings.TrimLeft(s, " \t,")
+			continue
+		}
+
+		var value string
+		value, s = readValue(strings.TrimLeft(s[1:], " \t"))
+		s = strings.TrimLeft(s, " \t,")
+
+		if !inBearer {
+			continue
+		}
+		switch strings.ToLower(token) {
+		case "error":
+			c.Error = value
+		case "scope":
+			c.Scope = value
+		case "resource_metadata":
+			c.ResourceMetadata = value
+		}
+	}
+
+	return c
+}
+
+// readToken reads an RFC 7230 token from the start of s.
+func readToken(s string) (token, rest string) {
+	i := strings.IndexAny(s, " \t,=\"")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// readValue reads a token or quoted-string from the start of s.
+func readValue(s string) (value, rest string) {
+	if !strings.HasPrefix(s, `"`) {
+		return readToken(s)
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
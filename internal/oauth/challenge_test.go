@@ -0,0 +1,62 @@
+package oauth
+
+import "testing"
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   Challenge
+	}{
+		{
+			name:   "empty",
+			header: "",
+			want:   Challenge{},
+		},
+		{
+			name:   "bare bearer",
+			header: "Bearer",
+			want:   Challenge{},
+		},
+		{
+			name:   "resource metadata and scope",
+			header: `Bearer resource_metadata="https://example.com/.well-known/oauth-protected-resource", scope="read write"`,
+			want: Challenge{
+				Scope:            "read write",
+				ResourceMetadata: "https://example.com/.well-known/oauth-protected-resource",
+			},
+		},
+		{
+			name:   "insufficient scope",
+			header: `Bearer error="insufficient_scope", scope="files:write", error_description="Need \"files:write\""`,
+			want:   Challenge{Error: "insufficient_scope", Scope: "files:write"},
+		},
+		{
+			name:   "unquoted values",
+			header: `bearer error=invalid_token`,
+			want:   Challenge{Error: "invalid_token"},
+		},
+		{
+			name:   "ignores other schemes",
+			header: `Basic realm="x", scope="ignored", Bearer scope="used"`,
+			want:   Challenge{Scope: "used"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseChallenge(tt.header); got != tt.want {
+				t.Errorf("ParseChallenge(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChallenge_InsufficientScope(t *testing.T) {
+	c := ParseChallenge(`Bearer error="insufficient_scope", scope="a b"`)
+	if !c.InsufficientScope() {
+		t.Error("InsufficientScope() = false, want true")
+	}
+	if scopes := c.Scopes(); len(scopes) != 2 || scopes[0] != "a" || scopes[1] != "b" {
+		t.Errorf("Scopes() = %v, want [a b]", scopes)
+	}
+}
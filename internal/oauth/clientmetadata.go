@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ClientMetadata is an OAuth Client ID Metadata Document: client metadata
+// published at an HTTPS URL, which is then used as the client_id instead of
+// registering the client with each authorization server.
+type ClientMetadata struct {
+	ClientID     string   `json:"client_id"`
+	ClientName   string   `json:"client_name,omitempty"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+// IsClientMetadataURL returns true if the client id is the URL of a client ID
+// metadata document (an https URL with a path component).
+func IsClientMetadataURL(clientID string) bool {
+	parsed, err := url.Parse(clientID)
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == "https" && parsed.Host != "" && parsed.Path != "" && parsed.Path != "/"
+}
+
+// FetchClientMetadata fetches the client ID metadata document at the given URL
+// and checks that it describes that client id.
+func FetchClientMetadata(httpClient *http.Client, clientID string) (*ClientMetadata, error) {
+	body, err := fetchJSON(httpClient, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta ClientMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse client metadata JSON: %w", err)
+	}
+
+	if meta.ClientID != clientID {
+		return nil, fmt.Errorf("client metadata document has client_id %q, want %q", meta.ClientID, clientID)
+	}
+
+	return &meta, nil
+}
+
+// AllowsRedirect returns true if the document lists the redirect URI.
+// Loopback redirect URIs match on any port (RFC 8252 section 7.3).
+func (m *ClientMetadata) AllowsRedirect(redirectURI string) bool {
+	want, err := url.Parse(redirectURI)
+	if err != nil {
+		return false
+	}
+
+	for _, registered := range m.RedirectURIs {
+		if registered == redirectURI {
+			return true
+		}
+		got, err := url.Parse(registered)
+		if err != nil {
+			continue
+		}
+		if isLoopback(got.Hostname()) && got.Scheme == want.Scheme && got.Hostname() == want.Hostname() && got.Path == want.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback returns true if host is a loopback IP address.
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsClientMetadataURL(t *testing.T) {
+	tests := []struct {
+		clientID string
+		want     bool
+	}{
+		{"https://mcpli.example.com/client.json", true},
+		{"https://mcpli.example.com/", false},
+		{"http://mcpli.example.com/client.json", false},
+		{"Iv1.abc123", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsClientMetadataURL(tt.clientID); got != tt.want {
+			t.Errorf("IsClientMetadataURL(%q) = %v, want %v", tt.clientID, got, tt.want)
+		}
+	}
+}
+
+func TestFetchClientMetadata(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID := server.URL + "/client.json"
+		if r.URL.Path == "/mismatch.json" {
+			clientID = "https://other.example.com/client.json"
+		}
+		json.NewEncoder(w).Encode(ClientMetadata{
+			ClientID:     clientID,
+			RedirectURIs: []string{"http://127.0.0.1/oauth/callback"},
+		})
+	}))
+	defer server.Close()
+
+	meta, err := FetchClientMetadata(http.DefaultClient, server.URL+"/client.json")
+	if err != nil {
+		t.Fatalf("FetchClientMetadata() error: %v", err)
+	}
+	if len(meta.RedirectURIs) != 1 {
+		t.Errorf("RedirectURIs = %v, want one entry", meta.RedirectURIs)
+	}
+
+	if _, err := FetchClientMetadata(http.DefaultClient, server.URL+"/mismatch.json"); err == nil {
+		t.Error("expected error when client_id does not match the document URL")
+	}
+}
+
+func TestClientMetadata_AllowsRedirect(t *testing.T) {
+	meta := &ClientMetadata{RedirectURIs: []string{
+		"http://127.0.0.1/oauth/callback",
+		"https://app.example.com/callback",
+	}}
+
+	tests := []struct {
+		redirectURI string
+		want        bool
+	}{
+		{"http://127.0.0.1:19877/oauth/callback", true},
+		{"http://127.0.0.1:55555/oauth/callback", true},
+		{"http://127.0.0.1:19877/other", false},
+		{"https://app.example.com/callback", true},
+		{"https://app.example.com:8443/callback", false},
+	}
+	for _, tt := range tests {
+		if got := meta.AllowsRedirect(tt.redirectURI); got != tt.want {
+			t.Errorf("AllowsRedirect(%q) = %v, want %v", tt.redirectURI, got, tt.want)
+		}
+	}
+}
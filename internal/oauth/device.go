@@ -0,0 +1,165 @@
+package oauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// defaultPollInterval is the polling interval when the server does not specify one.
+const defaultPollInterval = 5 * time.Second
+
+// deviceSleep waits between token polls. Tests replace it to avoid real delays.
+var deviceSleep = time.Sleep
+
+// deviceAuthorizationResponse is the device authorization endpoint response (RFC 8628 section 3.2).
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// authorizeDevice runs the device authorization grant (RFC 8628): it prints
+// the verification URI and user code, then polls the token endpoint until the
+// user has approved the request.
+func authorizeDevice(req *authRequest) (*tokenResponse, error) {
+	meta := req.meta
+	if meta.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("server does not support the device authorization grant")
+	}
+
+	if req.clientID == "" {
+		if meta.RegistrationEndpoint == "" {
+			return nil, fmt.Errorf("server does not support dynamic client registration; register a client with the authorization server and pass its --client-id (and --client-secret)")
+		}
+
+		fmt.Println("Registering client...")
+		clientID, clientSecret, err := RegisterDeviceClient(req.httpClient, meta.RegistrationEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("client registration failed: %w", err)
+		}
+		req.clientID, req.clientSecret, req.expandSecret = clientID, clientSecret, false
+		req.redirectURI = ""
+	}
+
+	data := url.Values{}
+	setClient(data, req.clientID, req.secret())
+	if req.scope != "" {
+		data.Set("scope", req.scope)
+	}
+	if req.resource != "" {
+		data.Set("resource", req.resource)
+	}
+
+	device, err := requestDeviceCode(req.httpClient, meta.DeviceAuthorizationEndpoint, data)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	fmt.Printf("To authenticate, open this URL in a browser:\n  %s\n", device.VerificationURI)
+	fmt.Printf("and enter the code: %s\n", device.UserCode)
+	if device.VerificationURIComplete != "" {
+		fmt.Printf("Or open this URL directly:\n  %s\n", device.VerificationURIComplete)
+	}
+	fmt.Println("Waiting for authorization...")
+
+	tokenData := url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"device_code": {device.DeviceCode},
+	}
+	setClient(tokenData, req.clientID, req.secret())
+	if req.resource != "" {
+		tokenData.Set("resource", req.resource)
+	}
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	expiresIn := time.Duration(device.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 5 * time.Minute
+	}
+
+	tokens, err := pollDeviceToken(req.httpClient, meta.TokenEndpoint, tokenData, interval, time.Now().Add(expiresIn))
+	if err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func requestDeviceCode(httpClient *http.Client, endpoint string, data url.Values) (*deviceAuthorizationResponse, error) {
+	resp, err := httpClient.Post(endpoint, "application/x-www-form-urlencoded", bytes.NewBufferString(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device authorization response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device authorization endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var device deviceAuthorizationResponse
+	if err := json.Unmarshal(body, &device); err != nil {
+		return nil, fmt.Errorf("failed to parse device authorization response: %w", err)
+	}
+
+	if device.DeviceCode == "" || device.UserCode == "" || device.VerificationURI == "" {
+		return nil, fmt.Errorf("device authorization response missing device_code, user_code or verification_uri")
+	}
+
+	return &device, nil
+}
+
+// pollDeviceToken polls the token endpoint until the user approves or denies
+// the request or the device code expires. authorization_pending keeps polling
+// and slow_down increases the interval by 5 seconds (RFC 8628 section 3.5).
+func pollDeviceToken(httpClient *http.Client, tokenEndpoint string, data url.Values, interval time.Duration, deadline time.Time) (*tokenResponse, error) {
+	for {
+		deviceSleep(interval)
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("device code expired before authorization completed")
+		}
+
+		tokens, err := doTokenRequest(httpClient, tokenEndpoint, data)
+		if err == nil {
+			return tokens, nil
+		}
+
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			return nil, err
+		}
+
+		switch tokenErr.Code {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		case "access_denied":
+			return nil, fmt.Errorf("authorization was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("device code expired before authorization completed")
+		default:
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func stubDeviceSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	original := deviceSleep
+	deviceSleep = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { deviceSleep = original })
+	return &waits
+}
+
+func TestAuthorizeDevice_PollsUntilApproved(t *testing.T) {
+	waits := stubDeviceSleep(t)
+	polls := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if got := r.PostForm.Get("client_id"); got != "client" {
+			t.Errorf("client_id = %q, want %q", got, "client")
+		}
+		if got := r.PostForm.Get("scope"); got != "read" {
+			t.Errorf("scope = %q, want %q", got, "read")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "dev-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://auth.example.com/device",
+			"expires_in":       600,
+			"interval":         2,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if got := r.PostForm.Get("grant_type"); got != deviceCodeGrantType {
+			t.Errorf("grant_type = %q, want %q", got, deviceCodeGrantType)
+		}
+		if got := r.PostForm.Get("device_code"); got != "dev-code" {
+			t.Errorf("device_code = %q, want %q", got, "dev-code")
+		}
+		polls++
+		switch polls {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"slow_down"}`))
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "device-token",
+				"expires_in":   3600,
+			})
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req := &authRequest{
+		httpClient: http.DefaultClient,
+		meta: &ServerMetadata{
+			TokenEndpoint:               server.URL + "/token",
+			DeviceAuthorizationEndpoint: server.URL + "/device",
+		},
+		clientID: "client",
+		scope:    "read",
+	}
+
+	tokens, err := authorizeDevice(req)
+	if err != nil {
+		t.Fatalf("authorizeDevice() error: %v", err)
+	}
+	if tokens.AccessToken != "device-token" {
+		t.Errorf("AccessToken = %q, want %q", tokens.AccessToken, "device-token")
+	}
+
+	want := []time.Duration{2 * time.Second, 2 * time.Second, 7 * time.Second}
+	if len(*waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", *waits, want)
+	}
+	for i := range want {
+		if (*waits)[i] != want[i] {
+			t.Errorf("wait %d = %v, want %v", i, (*waits)[i], want[i])
+		}
+	}
+}
+
+func TestPollDeviceToken_AccessDenied(t *testing.T) {
+	stubDeviceSleep(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"access_denied"}`))
+	}))
+	defer server.Close()
+
+	_, err := pollDeviceToken(http.DefaultClient, server.URL, nil, time.Second, time.Now().Add(time.Minute))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestAuthorizeDevice_Unsupported(t *testing.T) {
+	req := &authRequest{meta: &ServerMetadata{TokenEndpoint: "https://auth.example.com/token"}, clientID: "client"}
+	if _, err := authorizeDevice(req); err == nil {
+		t.Fatal("expected error when device_authorization_endpoint is missing")
+	}
+}
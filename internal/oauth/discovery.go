@@ -12,17 +12,28 @@ import (
 // ServerMetadata holds the OAuth authorization server metadata
 // from the well-known discovery endpoint.
 type ServerMetadata struct {
-	AuthorizationEndpoint string `json:"authorization_endpoint"`
-	TokenEndpoint         string `json:"token_endpoint"`
-	RegistrationEndpoint  string `json:"registration_endpoint"`
+	Issuer                            string `json:"issuer,omitempty"`
+	AuthorizationEndpoint             string `json:"authorization_endpoint"`
+	TokenEndpoint                     string `json:"token_endpoint"`
+	RegistrationEndpoint              string `json:"registration_endpoint"`
+	DeviceAuthorizationEndpoint       string `json:"device_authorization_endpoint,omitempty"`
+	RevocationEndpoint                string `json:"revocation_endpoint,omitempty"`
+	ClientIDMetadataDocumentSupported bool   `json:"client_id_metadata_document_supported,omitempty"`
 }
 
 // Discover fetches OAuth authorization server metadata for the given server URL.
 // It tries {origin}/.well-known/oauth-authorization-server first.
-func Discover(serverURL string) (*ServerMetadata, error) {
+func Discover(httpClient *http.Client, serverURL string) (*ServerMetadata, error) {
+	meta, _, err := discover(httpClient, serverURL)
+	return meta, err
+}
+
+// discover fetches the authorization server metadata and returns the
+// response headers, for caching.
+func discover(httpClient *http.Client, serverURL string) (*ServerMetadata, http.Header, error) {
 	parsed, err := url.Parse(serverURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid server URL: %w", err)
+		return nil, nil, fmt.Errorf("invalid server URL: %w", err)
 	}
 
 	origin := parsed.Scheme + "://" + parsed.Host
@@ -32,44 +43,105 @@ func Discover(serverURL string) (*ServerMetadata, error) {
 	path := strings.TrimRight(parsed.Path, "/")
 	if path != "" {
 		wellKnownURL := origin + "/.well-known/oauth-authorization-server" + path
-		meta, err := fetchMetadata(wellKnownURL)
+		meta, header, err := fetchMetadata(httpClient, wellKnownURL)
 		if err == nil {
-			return meta, nil
+			return meta, header, nil
 		}
 	}
 
 	// Fall back to origin-level well-known URL
 	wellKnownURL := origin + "/.well-known/oauth-authorization-server"
-	return fetchMetadata(wellKnownURL)
+	return fetchMetadata(httpClient, wellKnownURL)
+}
+
+// ResourceMetadata holds OAuth protected resource metadata (RFC 9728).
+type ResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers,omitempty"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
 }
 
-func fetchMetadata(url string) (*ServerMetadata, error) {
-	resp, err := http.Get(url)
+// DiscoverResource fetches the protected resource metadata for the given
+// server URL. If metadataURL is set (from the resource_metadata parameter of a
+// WWW-Authenticate challenge) it is used directly; otherwise the path-aware and
+// origin-level {origin}/.well-known/oauth-protected-resource URLs are tried.
+func DiscoverResource(httpClient *http.Client, serverURL, metadataURL string) (*ResourceMetadata, error) {
+	if metadataURL != "" {
+		return fetchResourceMetadata(httpClient, metadataURL)
+	}
+
+	parsed, err := url.Parse(serverURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch metadata from %s: %w", url, err)
+		return nil, fmt.Errorf("invalid server URL: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("metadata endpoint %s returned status %d", url, resp.StatusCode)
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	path := strings.TrimRight(parsed.Path, "/")
+	if path != "" {
+		meta, err := fetchResourceMetadata(httpClient, origin+"/.well-known/oauth-protected-resource"+path)
+		if err == nil {
+			return meta, nil
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return fetchResourceMetadata(httpClient, origin+"/.well-known/oauth-protected-resource")
+}
+
+func fetchResourceMetadata(httpClient *http.Client, url string) (*ResourceMetadata, error) {
+	body, err := fetchJSON(httpClient, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read metadata response: %w", err)
+		return nil, err
 	}
 
-	var meta ServerMetadata
+	var meta ResourceMetadata
 	if err := json.Unmarshal(body, &meta); err != nil {
-		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse resource metadata JSON: %w", err)
+	}
+
+	return &meta, nil
+}
+
+func fetchMetadata(httpClient *http.Client, url string) (*ServerMetadata, http.Header, error) {
+	body, header, err := fetchDocument(httpClient, url)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if meta.AuthorizationEndpoint == "" {
-		return nil, fmt.Errorf("metadata missing authorization_endpoint")
+	var meta ServerMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
 	}
+
 	if meta.TokenEndpoint == "" {
-		return nil, fmt.Errorf("metadata missing token_endpoint")
+		return nil, nil, fmt.Errorf("metadata missing token_endpoint")
 	}
 
-	return &meta, nil
+	return &meta, header, nil
+}
+
+// fetchJSON GETs a metadata document and returns its body.
+func fetchJSON(httpClient *http.Client, url string) ([]byte, error) {
+	body, _, err := fetchDocument(httpClient, url)
+	return body, err
+}
+
+// fetchDocument GETs a metadata document and returns its body and response headers.
+func fetchDocument(httpClient *http.Client, url string) ([]byte, http.Header, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch metadata from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("metadata endpoint %s returned status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read metadata response: %w", err)
+	}
+
+	return body, resp.Header, nil
 }
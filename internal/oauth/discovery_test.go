@@ -25,7 +25,7 @@ func TestDiscover_ValidMetadata(t *testing.T) {
 	}))
 	defer server.Close()
 
-	result, err := Discover(server.URL)
+	result, err := Discover(http.DefaultClient, server.URL)
 	if err != nil {
 		t.Fatalf("Discover() error: %v", err)
 	}
@@ -57,7 +57,7 @@ func TestDiscover_PathAware(t *testing.T) {
 	}))
 	defer server.Close()
 
-	result, err := Discover(server.URL + "/mcp/default")
+	result, err := Discover(http.DefaultClient, server.URL+"/mcp/default")
 	if err != nil {
 		t.Fatalf("Discover() error: %v", err)
 	}
@@ -77,7 +77,7 @@ func TestDiscover_MissingEndpoints(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, err := Discover(server.URL)
+	_, err := Discover(http.DefaultClient, server.URL)
 	if err == nil {
 		t.Fatal("Discover() should fail when token_endpoint is missing")
 	}
@@ -86,13 +86,32 @@ func TestDiscover_MissingEndpoints(t *testing.T) {
 	}
 }
 
+func TestDiscover_TokenEndpointOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":         "https://auth.example.com",
+			"token_endpoint": "https://auth.example.com/token",
+		})
+	}))
+	defer server.Close()
+
+	meta, err := Discover(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatalf("Discover() error: %v", err)
+	}
+	if meta.TokenEndpoint != "https://auth.example.com/token" || meta.AuthorizationEndpoint != "" {
+		t.Errorf("metadata = %+v", meta)
+	}
+}
+
 func TestDiscover_NotFound(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}))
 	defer server.Close()
 
-	_, err := Discover(server.URL)
+	_, err := Discover(http.DefaultClient, server.URL)
 	if err == nil {
 		t.Fatal("Discover() should fail when metadata endpoint returns 404")
 	}
@@ -105,8 +124,54 @@ func TestDiscover_InvalidJSON(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, err := Discover(server.URL)
+	_, err := Discover(http.DefaultClient, server.URL)
 	if err == nil {
 		t.Fatal("Discover() should fail on non-JSON response")
 	}
 }
+
+func TestDiscoverResource_WellKnown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/oauth-protected-resource/mcp" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ResourceMetadata{
+				Resource:        "https://api.example.com/mcp",
+				ScopesSupported: []string{"read", "write"},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	result, err := DiscoverResource(http.DefaultClient, server.URL+"/mcp", "")
+	if err != nil {
+		t.Fatalf("DiscoverResource() error: %v", err)
+	}
+	if result.Resource != "https://api.example.com/mcp" {
+		t.Errorf("Resource = %q, want %q", result.Resource, "https://api.example.com/mcp")
+	}
+	if len(result.ScopesSupported) != 2 {
+		t.Errorf("ScopesSupported = %v, want [read write]", result.ScopesSupported)
+	}
+}
+
+func TestDiscoverResource_ChallengeURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/custom-metadata" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ResourceMetadata{Resource: "https://api.example.com/"})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	result, err := DiscoverResource(http.DefaultClient, "https://unused.example.com/mcp", server.URL+"/custom-metadata")
+	if err != nil {
+		t.Fatalf("DiscoverResource() error: %v", err)
+	}
+	if result.Resource != "https://api.example.com/" {
+		t.Errorf("Resource = %q, want %q", result.Resource, "https://api.example.com/")
+	}
+}
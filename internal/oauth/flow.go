@@ -10,7 +10,11 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/juanibiapina/mcpli/internal/config"
 )
 
 // tokenResponse is the OAuth token endpoint response.
@@ -19,80 +23,343 @@ type tokenResponse struct {
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// AuthOptions customizes the authorization flow.
+type AuthOptions struct {
+	// Scopes to request. When empty, the scope of the challenge or the
+	// scopes_supported of the protected resource metadata are requested.
+	Scopes []string
+	// Challenge is the WWW-Authenticate header of the response that
+	// triggered authentication, if any.
+	Challenge string
+	// Device selects the device authorization grant (RFC 8628) instead of
+	// the browser-based authorization code flow.
+	Device bool
+	// ClientCredentials selects the client credentials grant for
+	// machine-to-machine authentication. It requires ClientID.
+	ClientCredentials bool
+	// ClientID and ClientSecret identify a pre-registered client, for
+	// authorization servers without dynamic client registration. The client
+	// id may be the URL of a client ID metadata document. The secret may
+	// contain ${VAR} references; only the reference is stored.
+	ClientID     string
+	ClientSecret string
+	// RedirectHost is the loopback IP address of the redirect URI:
+	// 127.0.0.1 (default) or ::1.
+	RedirectHost string
+	// RedirectPort requires an exact redirect URI port, for authorization
+	// servers that match pre-registered redirect URIs exactly.
+	// RedirectPortRange (e.g. "50000-50100") restricts the port to a range.
+	// When neither is set an ephemeral port is used.
+	RedirectPort      int
+	RedirectPortRange string
+	// Identity names the account the credentials are stored under, so a
+	// server can hold credentials for several accounts. Empty selects the
+	// default identity.
+	Identity string
 }
 
-// Authenticate runs the full OAuth authorization code flow with PKCE.
-// It performs discovery, client registration (if needed), opens the browser,
-// waits for the callback, and exchanges the code for tokens.
-func Authenticate(serverURL string) error {
+// callbackConfig returns the callback server config selected by the options.
+func (o AuthOptions) callbackConfig() (CallbackConfig, error) {
+	cfg := CallbackConfig{Host: o.RedirectHost}
+	switch {
+	case o.RedirectPort != 0:
+		cfg.PortMin, cfg.PortMax = o.RedirectPort, o.RedirectPort
+	case o.RedirectPortRange != "":
+		min, max, err := ParsePortRange(o.RedirectPortRange)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.PortMin, cfg.PortMax = min, max
+	}
+	return cfg, nil
+}
+
+// GrantClientCredentials is the OAuth client credentials grant type.
+const GrantClientCredentials = "client_credentials"
+
+// grantType returns the grant type selected by the options, as stored in
+// AuthEntry.GrantType. The authorization code flow is the empty string.
+func (o AuthOptions) grantType() string {
+	switch {
+	case o.ClientCredentials:
+		return GrantClientCredentials
+	case o.Device:
+		return deviceCodeGrantType
+	}
+	return ""
+}
+
+// authRequest holds the parameters shared by the authorization grants.
+type authRequest struct {
+	httpClient   *http.Client
+	meta         *ServerMetadata
+	clientID     string
+	clientSecret string
+	scope        string
+	resource     string
+	callback     CallbackConfig
+	// preRegistered is set when the client was given by the user rather
+	// than registered dynamically.
+	preRegistered bool
+	// expandSecret is set when clientSecret was given by the user and may
+	// hold ${VAR} references (see AuthEntry.ExpandSecret).
+	expandSecret bool
+	// redirectURI is the redirect URI the client was registered with.
+	redirectURI string
+}
+
+// secret returns the client secret to send, with the ${VAR} references of a
+// user-supplied secret expanded.
+func (req *authRequest) secret() string {
+	if req.expandSecret {
+		return config.ExpandEnv(req.clientSecret)
+	}
+	return req.clientSecret
+}
+
+// Authenticate runs an interactive OAuth flow for the server: the
+// authorization code flow with PKCE, or the device authorization grant when
+// opts.Device is set. It performs discovery, client registration (if needed),
+// lets the user authorize, and exchanges the grant for tokens.
+// Returns the stored credentials.
+func Authenticate(httpClient *http.Client, serverURL string, opts AuthOptions) (*AuthEntry, error) {
 	fmt.Println("OAuth authentication required. Starting authorization flow...")
 
-	// 1. Discovery
-	meta, err := Discover(serverURL)
+	callback, err := opts.callbackConfig()
 	if err != nil {
-		return fmt.Errorf("OAuth discovery failed: %w", err)
+		return nil, err
 	}
 
-	redirectURI := RedirectURI()
-
-	// 2. Load store and check for existing client registration
+	// 1. Load store to reuse cached metadata and an existing client registration
 	store, err := LoadStore()
 	if err != nil {
-		return fmt.Errorf("failed to load auth store: %w", err)
+		return nil, fmt.Errorf("failed to load auth store: %w", err)
 	}
 
-	entry := store.Entries[serverURL]
-	var clientID, clientSecret string
+	// 2. Discovery
+	meta, err := discoverCached(httpClient, store, serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("OAuth discovery failed: %w", err)
+	}
 
-	if entry != nil && entry.ClientID != "" {
-		clientID = entry.ClientID
-		clientSecret = entry.ClientSecret
-	} else {
-		// 3. Dynamic client registration
+	req := &authRequest{httpClient: httpClient, meta: meta, callback: callback}
+	req.resource, req.scope = resolveScope(httpClient, serverURL, opts)
+
+	if opts.ClientID != "" {
+		req.clientID = opts.ClientID
+		req.clientSecret = opts.ClientSecret
+		req.preRegistered = true
+		req.expandSecret = true
+		if existing := store.Entry(serverURL, opts.Identity, meta.Issuer); existing != nil {
+			req.redirectURI = existing.RedirectURI
+		}
+	} else if existing := registeredClient(store, serverURL, opts.Identity, meta.Issuer, opts.grantType()); existing != nil {
+		req.clientID = existing.ClientID
+		req.clientSecret = existing.ClientSecret
+		req.expandSecret = existing.ExpandSecret
+		req.redirectURI = existing.registeredRedirectURI()
+	}
+
+	// 3. Run the grant
+	var tokens *tokenResponse
+	grantType := opts.grantType()
+	switch grantType {
+	case GrantClientCredentials:
+		tokens, err = authorizeClientCredentials(req)
+	case deviceCodeGrantType:
+		tokens, err = authorizeDevice(req)
+	default:
+		tokens, err = authorizeBrowser(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Store credentials
+	scope := req.scope
+	if tokens.Scope != "" {
+		scope = tokens.Scope
+	}
+	entry := &AuthEntry{
+		ClientID:     req.clientID,
+		ClientSecret: req.clientSecret,
+		ExpandSecret: req.expandSecret,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+		TokenType:    tokens.TokenType,
+		Scope:        scope,
+		Resource:     req.resource,
+		GrantType:    grantType,
+		RedirectURI:  req.redirectURI,
+		Issuer:       meta.Issuer,
+	}
+	store.SetEntry(serverURL, opts.Identity, entry)
+
+	if err := store.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save auth credentials: %w", err)
+	}
+
+	fmt.Println("Authentication successful.")
+	return entry, nil
+}
+
+// registeredClient returns the entry whose client registration can be reused
+// for an identity and grant type: its own entry, or else that of another
+// identity of the same server. Clients registered with a different issuer or
+// for another grant type are not reused, since the authorization server
+// rejects grants a client was not registered for.
+func registeredClient(store *AuthStore, serverURL, identity, issuer, grantType string) *AuthEntry {
+	candidates := []*AuthEntry{store.Entry(serverURL, identity, issuer)}
+	for _, other := range store.Identities(serverURL) {
+		candidates = append(candidates, store.Entry(serverURL, other, issuer))
+	}
+
+	for _, entry := range candidates {
+		if entry == nil || entry.ClientID == "" {
+			continue
+		}
+		if issuer != "" && entry.Issuer != "" && entry.Issuer != issuer {
+			continue
+		}
+		if entry.GrantType != grantType {
+			continue
+		}
+		return entry
+	}
+	return nil
+}
+
+// resolveScope determines the RFC 8707 resource indicator and the scope to
+// request. Protected resource metadata is optional; it only refines the
+// resource indicator and default scopes.
+func resolveScope(httpClient *http.Client, serverURL string, opts AuthOptions) (resource, scope string) {
+	challenge := ParseChallenge(opts.Challenge)
+	resource = NormalizeURL(serverURL)
+	scopes := opts.Scopes
+	if len(scopes) == 0 {
+		scopes = challenge.Scopes()
+	}
+	if resourceMeta, err := DiscoverResource(httpClient, serverURL, challenge.ResourceMetadata); err == nil {
+		if resourceMeta.Resource != "" {
+			resource = resourceMeta.Resource
+		}
+		if len(scopes) == 0 {
+			scopes = resourceMeta.ScopesSupported
+		}
+	}
+	return resource, strings.Join(scopes, " ")
+}
+
+// authorizeClientCredentials obtains a token with the client credentials grant.
+func authorizeClientCredentials(req *authRequest) (*tokenResponse, error) {
+	if req.clientID == "" {
+		return nil, fmt.Errorf("client credentials grant requires a client id")
+	}
+
+	tokens, err := clientCredentialsToken(req.httpClient, req.meta.TokenEndpoint, req.clientID, req.secret(), req.scope, req.resource)
+	if err != nil {
+		return nil, fmt.Errorf("client credentials grant failed: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// authorizeBrowser runs the authorization code flow with PKCE, registering a
+// client first if req has none or its registration no longer matches the
+// redirect URI.
+func authorizeBrowser(req *authRequest) (*tokenResponse, error) {
+	meta := req.meta
+	if meta.AuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("server metadata has no authorization_endpoint; use the client credentials grant if the server only supports it")
+	}
+
+	// Start callback server BEFORE opening browser to avoid race condition.
+	// It also determines the redirect URI: prefer the port of the existing
+	// registration so it can be reused.
+	callback := req.callback
+	if req.redirectURI != "" {
+		if parsed, err := url.Parse(req.redirectURI); err == nil {
+			callback.Preferred, _ = strconv.Atoi(parsed.Port())
+		}
+	}
+	callbackServer, err := StartCallbackServer(callback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback server: %w", err)
+	}
+	defer callbackServer.Close()
+	redirectURI := callbackServer.RedirectURI()
+
+	if IsClientMetadataURL(req.clientID) {
+		// The client id is a metadata document URL: the authorization server
+		// fetches it instead of requiring registration.
+		if !meta.ClientIDMetadataDocumentSupported {
+			fmt.Println("Warning: authorization server does not advertise client ID metadata document support")
+		}
+		doc, err := FetchClientMetadata(req.httpClient, req.clientID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client ID metadata document: %w", err)
+		}
+		if !doc.AllowsRedirect(redirectURI) {
+			return nil, fmt.Errorf("client ID metadata document does not list redirect URI %s", redirectURI)
+		}
+	}
+
+	// A dynamically registered client is bound to its redirect URI
+	if req.clientID != "" && !req.preRegistered && req.redirectURI != redirectURI {
+		fmt.Println("Redirect URI changed since the client was registered.")
+		req.clientID, req.clientSecret, req.expandSecret = "", "", false
+	}
+
+	if req.clientID == "" {
+		// Dynamic client registration
 		if meta.RegistrationEndpoint == "" {
-			return fmt.Errorf("server does not support dynamic client registration and no client is registered")
+			return nil, fmt.Errorf("server does not support dynamic client registration; register a client with the authorization server and pass its --client-id (and --client-secret)")
 		}
 
 		fmt.Println("Registering client...")
-		clientID, clientSecret, err = RegisterClient(meta.RegistrationEndpoint, redirectURI)
+		clientID, clientSecret, err := RegisterClient(req.httpClient, meta.RegistrationEndpoint, redirectURI)
 		if err != nil {
-			return fmt.Errorf("client registration failed: %w", err)
+			return nil, fmt.Errorf("client registration failed: %w", err)
 		}
+		req.clientID, req.clientSecret, req.expandSecret = clientID, clientSecret, false
 	}
+	req.redirectURI = redirectURI
 
-	// 4. Generate PKCE challenge
+	// Generate PKCE challenge
 	pkce, err := GeneratePKCE()
 	if err != nil {
-		return fmt.Errorf("failed to generate PKCE challenge: %w", err)
+		return nil, fmt.Errorf("failed to generate PKCE challenge: %w", err)
 	}
 
-	// 5. Generate random state for CSRF protection
+	// Generate random state for CSRF protection
 	stateBytes := make([]byte, 16)
 	if _, err := rand.Read(stateBytes); err != nil {
-		return fmt.Errorf("failed to generate state: %w", err)
+		return nil, fmt.Errorf("failed to generate state: %w", err)
 	}
 	state := base64.RawURLEncoding.EncodeToString(stateBytes)
 
-	// 6. Build authorization URL
+	// Build authorization URL
 	authURL, err := url.Parse(meta.AuthorizationEndpoint)
 	if err != nil {
-		return fmt.Errorf("invalid authorization endpoint: %w", err)
+		return nil, fmt.Errorf("invalid authorization endpoint: %w", err)
 	}
 
 	q := authURL.Query()
 	q.Set("response_type", "code")
-	q.Set("client_id", clientID)
+	q.Set("client_id", req.clientID)
 	q.Set("redirect_uri", redirectURI)
 	q.Set("code_challenge", pkce.CodeChallenge)
 	q.Set("code_challenge_method", "S256")
 	q.Set("state", state)
-	authURL.RawQuery = q.Encode()
-
-	// 7. Start callback server BEFORE opening browser to avoid race condition
-	callbackServer, err := StartCallbackServer()
-	if err != nil {
-		return fmt.Errorf("failed to start callback server: %w", err)
+	q.Set("resource", req.resource)
+	if req.scope != "" {
+		q.Set("scope", req.scope)
 	}
+	authURL.RawQuery = q.Encode()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -102,69 +369,107 @@ func Authenticate(serverURL string) error {
 
 	code, returnedState, err := callbackServer.Wait(ctx)
 	if err != nil {
-		return fmt.Errorf("authorization callback failed: %w", err)
+		return nil, fmt.Errorf("authorization callback failed: %w", err)
 	}
 
-	// 8. Verify state
+	// Verify state
 	if returnedState != state {
-		return fmt.Errorf("state mismatch: possible CSRF attack")
+		return nil, fmt.Errorf("state mismatch: possible CSRF attack")
+	}
+
+	// Exchange code for tokens
+	tokens, err := exchangeCode(req.httpClient, meta.TokenEndpoint, req.clientID, req.secret(), code, redirectURI, pkce.CodeVerifier, req.resource)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
 	}
 
-	// 9. Exchange code for tokens
-	tokens, err := exchangeCode(meta.TokenEndpoint, clientID, clientSecret, code, redirectURI, pkce.CodeVerifier)
+	return tokens, nil
+}
+
+// GetValidToken returns a valid access token for the given server URL,
+// identity and issuer. It refreshes the token if it's expired. An empty
+// issuer matches the credentials of a single issuer.
+func GetValidToken(httpClient *http.Client, serverURL, identity, issuer string) (string, error) {
+	store, err := LoadStore()
 	if err != nil {
-		return fmt.Errorf("token exchange failed: %w", err)
+		return "", fmt.Errorf("failed to load auth store: %w", err)
 	}
 
-	// 10. Store credentials
-	store.Entries[serverURL] = &AuthEntry{
-		ClientID:            clientID,
-		ClientSecret:        clientSecret,
-		AccessToken:         tokens.AccessToken,
-		RefreshToken:        tokens.RefreshToken,
-		ExpiresAt:           time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
-		TokenType:           tokens.TokenType,
+	entry := store.Entry(serverURL, identity, issuer)
+	if entry == nil || entry.AccessToken == "" {
+		return "", noCredentialsError(serverURL, identity)
+	}
+
+	if !entry.IsExpired() {
+		return entry.AccessToken, nil
+	}
+
+	if err := refreshEntry(httpClient, store, serverURL, entry); err != nil {
+		return "", err
 	}
 
 	if err := store.Save(); err != nil {
-		return fmt.Errorf("failed to save auth credentials: %w", err)
+		return "", fmt.Errorf("failed to save refreshed token: %w", err)
 	}
 
-	fmt.Println("Authentication successful.")
-	return nil
+	return entry.AccessToken, nil
 }
 
-// GetValidToken returns a valid access token for the given server URL.
-// It refreshes the token if it's expired.
-func GetValidToken(serverURL string) (string, error) {
+// Refresh obtains a new access token for the given server URL, identity and
+// issuer, even if the current one has not expired yet. Returns the updated
+// credentials.
+func Refresh(httpClient *http.Client, serverURL, identity, issuer string) (*AuthEntry, error) {
 	store, err := LoadStore()
 	if err != nil {
-		return "", fmt.Errorf("failed to load auth store: %w", err)
+		return nil, fmt.Errorf("failed to load auth store: %w", err)
 	}
 
-	entry, ok := store.Entries[serverURL]
-	if !ok {
-		return "", fmt.Errorf("no OAuth credentials found for %s", serverURL)
+	entry := store.Entry(serverURL, identity, issuer)
+	if entry == nil || entry.AccessToken == "" {
+		return nil, noCredentialsError(serverURL, identity)
 	}
 
-	if !entry.IsExpired() {
-		return entry.AccessToken, nil
+	if err := refreshEntry(httpClient, store, serverURL, entry); err != nil {
+		return nil, err
 	}
 
-	// Token is expired, try to refresh
-	if entry.RefreshToken == "" {
-		return "", fmt.Errorf("access token expired and no refresh token available")
+	if err := store.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save refreshed token: %w", err)
+	}
+
+	return entry, nil
+}
+
+// noCredentialsError reports that no tokens are stored for a server identity.
+func noCredentialsError(serverURL, identity string) error {
+	if identity != "" {
+		return fmt.Errorf("no OAuth credentials found for %s (identity %q)", serverURL, identity)
+	}
+	return fmt.Errorf("no OAuth credentials found for %s", serverURL)
+}
+
+// refreshEntry replaces the access token of an entry. Client credentials can
+// simply mint a new one; other grants need a refresh token.
+func refreshEntry(httpClient *http.Client, store *AuthStore, serverURL string, entry *AuthEntry) error {
+	clientCredentials := entry.GrantType == GrantClientCredentials
+	if !clientCredentials && entry.RefreshToken == "" {
+		return fmt.Errorf("access token expired and no refresh token available")
 	}
 
 	// Discover token endpoint
-	meta, err := Discover(serverURL)
+	meta, err := discoverCached(httpClient, store, serverURL)
 	if err != nil {
-		return "", fmt.Errorf("OAuth discovery failed during token refresh: %w", err)
+		return fmt.Errorf("OAuth discovery failed during token refresh: %w", err)
 	}
 
-	tokens, err := refreshToken(meta.TokenEndpoint, entry.ClientID, entry.ClientSecret, entry.RefreshToken)
+	var tokens *tokenResponse
+	if clientCredentials {
+		tokens, err = clientCredentialsToken(httpClient, meta.TokenEndpoint, entry.ClientID, entry.secret(), entry.Scope, entry.Resource)
+	} else {
+		tokens, err = refreshToken(httpClient, meta.TokenEndpoint, entry.ClientID, entry.secret(), entry.RefreshToken, entry.Resource)
+	}
 	if err != nil {
-		return "", fmt.Errorf("token refresh failed: %w", err)
+		return fmt.Errorf("token refresh failed: %w", err)
 	}
 
 	// Update stored tokens
@@ -173,44 +478,78 @@ func GetValidToken(serverURL string) (string, error) {
 	if tokens.RefreshToken != "" {
 		entry.RefreshToken = tokens.RefreshToken
 	}
-
-	if err := store.Save(); err != nil {
-		return "", fmt.Errorf("failed to save refreshed token: %w", err)
+	if tokens.Scope != "" {
+		entry.Scope = tokens.Scope
 	}
 
-	return entry.AccessToken, nil
+	return nil
+}
+
+// setClient adds the client credentials to a token request.
+func setClient(data url.Values, clientID, clientSecret string) {
+	data.Set("client_id", clientID)
+	if clientSecret != "" {
+		data.Set("client_secret", clientSecret)
+	}
 }
 
-func exchangeCode(tokenEndpoint, clientID, clientSecret, code, redirectURI, codeVerifier string) (*tokenResponse, error) {
+func exchangeCode(httpClient *http.Client, tokenEndpoint, clientID, clientSecret, code, redirectURI, codeVerifier, resource string) (*tokenResponse, error) {
 	data := url.Values{
 		"grant_type":    {"authorization_code"},
 		"code":          {code},
 		"redirect_uri":  {redirectURI},
 		"code_verifier": {codeVerifier},
-		"client_id":     {clientID},
 	}
-	if clientSecret != "" {
-		data.Set("client_secret", clientSecret)
+	setClient(data, clientID, clientSecret)
+	if resource != "" {
+		data.Set("resource", resource)
 	}
 
-	return doTokenRequest(tokenEndpoint, data)
+	return doTokenRequest(httpClient, tokenEndpoint, data)
 }
 
-func refreshToken(tokenEndpoint, clientID, clientSecret, refreshTok string) (*tokenResponse, error) {
+func refreshToken(httpClient *http.Client, tokenEndpoint, clientID, clientSecret, refreshTok, resource string) (*tokenResponse, error) {
 	data := url.Values{
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {refreshTok},
-		"client_id":     {clientID},
 	}
-	if clientSecret != "" {
-		data.Set("client_secret", clientSecret)
+	setClient(data, clientID, clientSecret)
+	if resource != "" {
+		data.Set("resource", resource)
+	}
+
+	return doTokenRequest(httpClient, tokenEndpoint, data)
+}
+
+func clientCredentialsToken(httpClient *http.Client, tokenEndpoint, clientID, clientSecret, scope, resource string) (*tokenResponse, error) {
+	data := url.Values{
+		"grant_type": {GrantClientCredentials},
 	}
+	setClient(data, clientID, clientSecret)
+	if scope != "" {
+		data.Set("scope", scope)
+	}
+	if resource != "" {
+		data.Set("resource", resource)
+	}
+
+	return doTokenRequest(httpClient, tokenEndpoint, data)
+}
 
-	return doTokenRequest(tokenEndpoint, data)
+// TokenError is an OAuth error response from the token endpoint (RFC 6749 section 5.2).
+type TokenError struct {
+	StatusCode  int
+	Code        string
+	Description string
+	Body        string
 }
 
-func doTokenRequest(tokenEndpoint string, data url.Values) (*tokenResponse, error) {
-	resp, err := http.Post(tokenEndpoint, "application/x-www-form-urlencoded", bytes.NewBufferString(data.Encode()))
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("token endpoint returned status %d: %s", e.StatusCode, e.Body)
+}
+
+func doTokenRequest(httpClient *http.Client, tokenEndpoint string, data url.Values) (*tokenResponse, error) {
+	resp, err := httpClient.Post(tokenEndpoint, "application/x-www-form-urlencoded", bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("token request failed: %w", err)
 	}
@@ -222,7 +561,16 @@ func doTokenRequest(tokenEndpoint string, data url.Values) (*tokenResponse, erro
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(body))
+		tokenErr := &TokenError{StatusCode: resp.StatusCode, Body: string(body)}
+		var errResp struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &errResp) == nil {
+			tokenErr.Code = errResp.Error
+			tokenErr.Description = errResp.ErrorDescription
+		}
+		return nil, tokenErr
 	}
 
 	var tokens tokenResponse
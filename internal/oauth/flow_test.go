@@ -0,0 +1,166 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExchangeCode_SendsResource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm() error: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "authorization_code" {
+			t.Errorf("grant_type = %q, want %q", got, "authorization_code")
+		}
+		if got := r.PostForm.Get("resource"); got != "https://example.com/mcp" {
+			t.Errorf("resource = %q, want %q", got, "https://example.com/mcp")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"scope":        "read write",
+		})
+	}))
+	defer server.Close()
+
+	tokens, err := exchangeCode(http.DefaultClient, server.URL, "client", "", "code", "http://127.0.0.1/cb", "verifier", "https://example.com/mcp")
+	if err != nil {
+		t.Fatalf("exchangeCode() error: %v", err)
+	}
+	if tokens.Scope != "read write" {
+		t.Errorf("Scope = %q, want %q", tokens.Scope, "read write")
+	}
+}
+
+func TestRefreshToken_SendsResource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm() error: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "refresh_token" {
+			t.Errorf("grant_type = %q, want %q", got, "refresh_token")
+		}
+		if got := r.PostForm.Get("resource"); got != "https://example.com/mcp" {
+			t.Errorf("resource = %q, want %q", got, "https://example.com/mcp")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token",
+			"expires_in":   3600,
+		})
+	}))
+	defer server.Close()
+
+	if _, err := refreshToken(http.DefaultClient, server.URL, "client", "", "refresh", "https://example.com/mcp"); err != nil {
+		t.Fatalf("refreshToken() error: %v", err)
+	}
+}
+
+func TestGetValidToken_ClientCredentialsRemint(t *testing.T) {
+	setTestStateHome(t)
+	t.Setenv("MCPLI_TEST_SECRET", "s3cret")
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			json.NewEncoder(w).Encode(ServerMetadata{
+				AuthorizationEndpoint: server.URL + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+			})
+		case "/token":
+			r.ParseForm()
+			if got := r.PostForm.Get("grant_type"); got != GrantClientCredentials {
+				t.Errorf("grant_type = %q, want %q", got, GrantClientCredentials)
+			}
+			if got := r.PostForm.Get("client_secret"); got != "s3cret" {
+				t.Errorf("client_secret = %q, want expanded secret", got)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "fresh-token",
+				"expires_in":   3600,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := &AuthStore{Entries: map[string]*AuthEntry{
+		server.URL: {
+			ClientID:     "service",
+			ClientSecret: "${MCPLI_TEST_SECRET}",
+			ExpandSecret: true,
+			AccessToken:  "stale-token",
+			ExpiresAt:    time.Now().Add(-time.Minute),
+			GrantType:    GrantClientCredentials,
+		},
+	}}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	token, err := GetValidToken(http.DefaultClient, server.URL, "", "")
+	if err != nil {
+		t.Fatalf("GetValidToken() error: %v", err)
+	}
+	if token != "fresh-token" {
+		t.Errorf("token = %q, want %q", token, "fresh-token")
+	}
+
+	loaded, err := LoadStore()
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	if got := loaded.Entries[server.URL].ClientSecret; got != "${MCPLI_TEST_SECRET}" {
+		t.Errorf("stored ClientSecret = %q, want the unexpanded reference", got)
+	}
+}
+
+func TestAuthEntrySecret(t *testing.T) {
+	t.Setenv("MCPLI_TEST_SECRET", "s3cret")
+
+	given := &AuthEntry{ClientSecret: "${MCPLI_TEST_SECRET}", ExpandSecret: true}
+	if got := given.secret(); got != "s3cret" {
+		t.Errorf("secret() of a user-supplied secret = %q, want %q", got, "s3cret")
+	}
+	// A secret issued by client registration is sent as is
+	registered := &AuthEntry{ClientSecret: "a${MCPLI_TEST_SECRET}b"}
+	if got := registered.secret(); got != "a${MCPLI_TEST_SECRET}b" {
+		t.Errorf("secret() of a registered secret = %q, want it unchanged", got)
+	}
+}
+
+func TestRegisteredClient(t *testing.T) {
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+	store.SetEntry("https://example.com/mcp", "", &AuthEntry{ClientID: "default-client", Issuer: "https://auth.example.com"})
+	store.SetEntry("https://example.com/mcp", "old", &AuthEntry{ClientID: "old-client", Issuer: "https://old-auth.example.com"})
+
+	// A new identity reuses the registration of another identity
+	if got := registeredClient(store, "https://example.com/mcp", "personal", "https://auth.example.com", ""); got == nil || got.ClientID != "default-client" {
+		t.Errorf("registeredClient() = %+v, want default-client", got)
+	}
+
+	// Registrations with another issuer are not reused
+	if got := registeredClient(store, "https://example.com/mcp", "old", "https://auth.example.com", ""); got == nil || got.ClientID != "default-client" {
+		t.Errorf("registeredClient() = %+v, want default-client", got)
+	}
+	if got := registeredClient(store, "https://example.com/mcp", "", "https://new-auth.example.com", ""); got != nil {
+		t.Errorf("registeredClient() = %+v, want nil for a new issuer", got)
+	}
+
+	// Registrations for another grant type are not reused
+	if got := registeredClient(store, "https://example.com/mcp", "", "https://auth.example.com", deviceCodeGrantType); got != nil {
+		t.Errorf("registeredClient() = %+v, want nil for the device grant", got)
+	}
+	store.SetEntry("https://example.com/mcp", "ci", &AuthEntry{ClientID: "device-client", Issuer: "https://auth.example.com", GrantType: deviceCodeGrantType})
+	if got := registeredClient(store, "https://example.com/mcp", "", "https://auth.example.com", deviceCodeGrantType); got == nil || got.ClientID != "device-client" {
+		t.Errorf("registeredClient() = %+v, want device-client", got)
+	}
+}
@@ -0,0 +1,114 @@
+package oauth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMetadataTTL is how long discovered metadata is used without
+// rediscovery when the server sends no cache headers.
+const defaultMetadataTTL = 24 * time.Hour
+
+// CachedMetadata is authorization server metadata cached in the auth store.
+type CachedMetadata struct {
+	ServerMetadata
+	FetchedAt time.Time `json:"fetched_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IsFresh returns true if the cached metadata can be used without rediscovery.
+func (c *CachedMetadata) IsFresh() bool {
+	return time.Now().Before(c.ExpiresAt)
+}
+
+// discoverCached returns the authorization server metadata for a server from
+// the store's cache while it is fresh. Otherwise the metadata is discovered
+// again and cached for as long as the response's cache headers allow; if
+// discovery fails, a stale cached copy is used. The caller saves the store.
+func discoverCached(httpClient *http.Client, store *AuthStore, serverURL string) (*ServerMetadata, error) {
+	key := NormalizeURL(serverURL)
+	cached := store.Metadata[key]
+	if cached != nil && cached.IsFresh() {
+		return &cached.ServerMetadata, nil
+	}
+
+	meta, header, err := discover(httpClient, serverURL)
+	if err != nil {
+		if cached != nil {
+			return &cached.ServerMetadata, nil
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	ttl, cacheable := cacheLifetime(header, now)
+	if !cacheable {
+		delete(store.Metadata, key)
+		return meta, nil
+	}
+
+	if store.Metadata == nil {
+		store.Metadata = make(map[string]*CachedMetadata)
+	}
+	store.Metadata[key] = &CachedMetadata{
+		ServerMetadata: *meta,
+		FetchedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+	}
+	return meta, nil
+}
+
+// cacheLifetime returns how long a response may be cached according to its
+// Cache-Control and Expires headers, or defaultMetadataTTL without either.
+// no-cache responses are kept only as a fallback for failed discovery;
+// no-store responses are not cached at all.
+func cacheLifetime(header http.Header, now time.Time) (time.Duration, bool) {
+	// Read every directive first: no-store wins over no-cache, and no-cache
+	// over max-age, whatever their order.
+	var noStore, noCache bool
+	maxAge := ""
+	for _, field := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(field, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store":
+				noStore = true
+			case "no-cache":
+				noCache = true
+			case "max-age":
+				maxAge = strings.Trim(value, `"`)
+			}
+		}
+	}
+	switch {
+	case noStore:
+		return 0, false
+	case noCache:
+		return 0, true
+	case maxAge != "":
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || seconds < 0 {
+			return 0, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0, true
+		}
+		date := now
+		if d, err := http.ParseTime(header.Get("Date")); err == nil {
+			date = d
+		}
+		if ttl := expiresAt.Sub(date); ttl > 0 {
+			return ttl, true
+		}
+		return 0, true
+	}
+
+	return defaultMetadataTTL, true
+}
@@ -0,0 +1,205 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheLifetime(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantTTL   time.Duration
+		wantCache bool
+	}{
+		{"no headers", http.Header{}, defaultMetadataTTL, true},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=3600"}}, time.Hour, true},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, 0, true},
+		{"no-store", http.Header{"Cache-Control": {"no-store"}}, 0, false},
+		{"invalid max-age", http.Header{"Cache-Control": {"max-age=soon"}}, 0, true},
+		{"no-store after max-age", http.Header{"Cache-Control": {"max-age=3600, no-store"}}, 0, false},
+		{"no-cache after max-age", http.Header{"Cache-Control": {"max-age=3600, no-cache"}}, 0, true},
+		{"no-store and no-cache", http.Header{"Cache-Control": {"no-cache, no-store"}}, 0, false},
+		{"directives in several fields", http.Header{"Cache-Control": {"max-age=3600", "no-store"}}, 0, false},
+		{"expires", http.Header{
+			"Date":    {"Thu, 01 Oct 2026 12:00:00 GMT"},
+			"Expires": {"Thu, 01 Oct 2026 14:00:00 GMT"},
+		}, 2 * time.Hour, true},
+		{"expires in the past", http.Header{"Expires": {"Thu, 01 Oct 2026 11:00:00 GMT"}}, 0, true},
+		{"max-age wins over expires", http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Expires":       {"Thu, 01 Oct 2026 14:00:00 GMT"},
+		}, time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl, cacheable := cacheLifetime(tt.header, now)
+			if ttl != tt.wantTTL || cacheable != tt.wantCache {
+				t.Errorf("cacheLifetime() = %v, %v; want %v, %v", ttl, cacheable, tt.wantTTL, tt.wantCache)
+			}
+		})
+	}
+}
+
+// metadataServer serves authorization server metadata and counts requests.
+// While failing is set it returns 503.
+type metadataServer struct {
+	*httptest.Server
+	requests     int
+	failing      bool
+	cacheControl string
+}
+
+func newMetadataServer(t *testing.T) *metadataServer {
+	t.Helper()
+	s := &metadataServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests++
+		if s.failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ServerMetadata{
+			AuthorizationEndpoint: s.URL + "/authorize",
+			TokenEndpoint:         s.URL + "/token",
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestDiscoverCached_UsesFreshCache(t *testing.T) {
+	server := newMetadataServer(t)
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+
+	for i := 0; i < 2; i++ {
+		meta, err := discoverCached(http.DefaultClient, store, server.URL)
+		if err != nil {
+			t.Fatalf("discoverCached() error: %v", err)
+		}
+		if meta.TokenEndpoint != server.URL+"/token" {
+			t.Errorf("TokenEndpoint = %q", meta.TokenEndpoint)
+		}
+	}
+
+	if server.requests != 1 {
+		t.Errorf("expected 1 discovery request, got %d", server.requests)
+	}
+	if cached := store.Metadata[NormalizeURL(server.URL)]; cached == nil || !cached.IsFresh() {
+		t.Errorf("expected fresh cached metadata, got %+v", cached)
+	}
+}
+
+func TestDiscoverCached_RediscoversStaleCache(t *testing.T) {
+	server := newMetadataServer(t)
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+	store.Metadata = map[string]*CachedMetadata{
+		NormalizeURL(server.URL): {
+			ServerMetadata: ServerMetadata{TokenEndpoint: "https://old.example.com/token"},
+			ExpiresAt:      time.Now().Add(-time.Minute),
+		},
+	}
+
+	meta, err := discoverCached(http.DefaultClient, store, server.URL)
+	if err != nil {
+		t.Fatalf("discoverCached() error: %v", err)
+	}
+	if meta.TokenEndpoint != server.URL+"/token" {
+		t.Errorf("TokenEndpoint = %q, want rediscovered endpoint", meta.TokenEndpoint)
+	}
+	if server.requests != 1 {
+		t.Errorf("expected 1 discovery request, got %d", server.requests)
+	}
+}
+
+func TestDiscoverCached_FallsBackToStaleCache(t *testing.T) {
+	server := newMetadataServer(t)
+	server.failing = true
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+	store.Metadata = map[string]*CachedMetadata{
+		NormalizeURL(server.URL): {
+			ServerMetadata: ServerMetadata{TokenEndpoint: "https://cached.example.com/token"},
+			ExpiresAt:      time.Now().Add(-time.Hour),
+		},
+	}
+
+	meta, err := discoverCached(http.DefaultClient, store, server.URL)
+	if err != nil {
+		t.Fatalf("discoverCached() error: %v", err)
+	}
+	if meta.TokenEndpoint != "https://cached.example.com/token" {
+		t.Errorf("TokenEndpoint = %q, want cached endpoint", meta.TokenEndpoint)
+	}
+
+	// Without a cached copy the failure is reported
+	delete(store.Metadata, NormalizeURL(server.URL))
+	if _, err := discoverCached(http.DefaultClient, store, server.URL); err == nil {
+		t.Error("expected error when discovery fails without a cached copy")
+	}
+}
+
+func TestDiscoverCached_NoStore(t *testing.T) {
+	server := newMetadataServer(t)
+	server.cacheControl = "no-store"
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+
+	if _, err := discoverCached(http.DefaultClient, store, server.URL); err != nil {
+		t.Fatalf("discoverCached() error: %v", err)
+	}
+	if _, err := discoverCached(http.DefaultClient, store, server.URL); err != nil {
+		t.Fatalf("discoverCached() error: %v", err)
+	}
+
+	if server.requests != 2 {
+		t.Errorf("expected 2 discovery requests, got %d", server.requests)
+	}
+	if len(store.Metadata) != 0 {
+		t.Errorf("no-store metadata should not be cached: %v", store.Metadata)
+	}
+}
+
+func TestGetValidToken_CachesMetadata(t *testing.T) {
+	setTestStateHome(t)
+
+	var discoveries int
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		discoveries++
+		json.NewEncoder(w).Encode(ServerMetadata{
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(tokenResponse{AccessToken: "new-token", TokenType: "Bearer", ExpiresIn: 0})
+	})
+
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+	store.SetEntry(server.URL, "", &AuthEntry{ClientID: "client", AccessToken: "old", RefreshToken: "refresh"})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	// The token expires immediately, so each call refreshes it
+	for i := 0; i < 2; i++ {
+		if _, err := GetValidToken(http.DefaultClient, server.URL, "", ""); err != nil {
+			t.Fatalf("GetValidToken() error: %v", err)
+		}
+	}
+
+	if discoveries != 1 {
+		t.Errorf("expected metadata to be discovered once, got %d", discoveries)
+	}
+}
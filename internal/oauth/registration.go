@@ -10,11 +10,11 @@ import (
 
 // registrationRequest is the dynamic client registration request body.
 type registrationRequest struct {
-	ClientName   string   `json:"client_name"`
-	RedirectURIs []string `json:"redirect_uris"`
-	GrantTypes   []string `json:"grant_types"`
-	ResponseTypes []string `json:"response_types"`
-	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+	ClientName              string   `json:"client_name"`
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
 }
 
 // registrationResponse is the dynamic client registration response.
@@ -25,21 +25,34 @@ type registrationResponse struct {
 
 // RegisterClient performs OAuth 2.0 Dynamic Client Registration.
 // Returns the client_id and optionally client_secret.
-func RegisterClient(registrationEndpoint string, redirectURI string) (clientID, clientSecret string, err error) {
-	reqBody := registrationRequest{
-		ClientName:   "mcpli",
-		RedirectURIs: []string{redirectURI},
-		GrantTypes:   []string{"authorization_code", "refresh_token"},
-		ResponseTypes: []string{"code"},
+func RegisterClient(httpClient *http.Client, registrationEndpoint string, redirectURI string) (clientID, clientSecret string, err error) {
+	return register(httpClient, registrationEndpoint, registrationRequest{
+		ClientName:              "mcpli",
+		RedirectURIs:            []string{redirectURI},
+		GrantTypes:              []string{"authorization_code", "refresh_token"},
+		ResponseTypes:           []string{"code"},
 		TokenEndpointAuthMethod: "none",
-	}
+	})
+}
+
+// RegisterDeviceClient registers a client for the device authorization grant.
+// Returns the client_id and optionally client_secret.
+func RegisterDeviceClient(httpClient *http.Client, registrationEndpoint string) (clientID, clientSecret string, err error) {
+	return register(httpClient, registrationEndpoint, registrationRequest{
+		ClientName:              "mcpli",
+		GrantTypes:              []string{deviceCodeGrantType, "refresh_token"},
+		ResponseTypes:           []string{},
+		TokenEndpointAuthMethod: "none",
+	})
+}
 
+func register(httpClient *http.Client, registrationEndpoint string, reqBody registrationRequest) (clientID, clientSecret string, err error) {
 	body, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to marshal registration request: %w", err)
 	}
 
-	resp, err := http.Post(registrationEndpoint, "application/json", bytes.NewReader(body))
+	resp, err := httpClient.Post(registrationEndpoint, "application/json", bytes.NewReader(body))
 	if err != nil {
 		return "", "", fmt.Errorf("registration request failed: %w", err)
 	}
@@ -33,7 +33,7 @@ func TestRegisterClient_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	clientID, clientSecret, err := RegisterClient(server.URL, "http://127.0.0.1:19877/oauth/callback")
+	clientID, clientSecret, err := RegisterClient(http.DefaultClient, server.URL, "http://127.0.0.1:19877/oauth/callback")
 	if err != nil {
 		t.Fatalf("RegisterClient() error: %v", err)
 	}
@@ -52,7 +52,7 @@ func TestRegisterClient_ServerError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, _, err := RegisterClient(server.URL, "http://127.0.0.1:19877/oauth/callback")
+	_, _, err := RegisterClient(http.DefaultClient, server.URL, "http://127.0.0.1:19877/oauth/callback")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -67,7 +67,7 @@ func TestRegisterClient_MissingClientID(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, _, err := RegisterClient(server.URL, "http://127.0.0.1:19877/oauth/callback")
+	_, _, err := RegisterClient(http.DefaultClient, server.URL, "http://127.0.0.1:19877/oauth/callback")
 	if err == nil {
 		t.Fatal("expected error for missing client_id, got nil")
 	}
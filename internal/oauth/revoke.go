@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// RevocationError reports that the tokens could not be revoked at the
+// authorization server.
+type RevocationError struct {
+	Err error
+}
+
+func (e *RevocationError) Error() string {
+	return fmt.Sprintf("failed to revoke tokens: %v", e.Err)
+}
+
+func (e *RevocationError) Unwrap() error {
+	return e.Err
+}
+
+// Logout revokes the tokens for the given server URL, identity and issuer at the authorization
+// server when it advertises a revocation endpoint (RFC 7009), then removes
+// them from the store. The client registration is kept for the next login.
+// Returns true if the tokens were revoked remotely. A failed revocation is
+// reported as a RevocationError, but the tokens are removed locally regardless.
+func Logout(httpClient *http.Client, serverURL, identity, issuer string) (bool, error) {
+	store, err := LoadStore()
+	if err != nil {
+		return false, fmt.Errorf("failed to load auth store: %w", err)
+	}
+
+	entry := store.Entry(serverURL, identity, issuer)
+	if entry == nil || entry.AccessToken == "" {
+		return false, noCredentialsError(serverURL, identity)
+	}
+
+	revoked := false
+	var revokeErr error
+	if meta, err := discoverCached(httpClient, store, serverURL); err == nil && meta.RevocationEndpoint != "" {
+		// Revoking the refresh token also invalidates its access tokens on
+		// most servers; revoke both to be safe.
+		if entry.RefreshToken != "" {
+			revokeErr = revokeToken(httpClient, meta.RevocationEndpoint, entry.ClientID, entry.secret(), entry.RefreshToken, "refresh_token")
+		}
+		if revokeErr == nil {
+			revokeErr = revokeToken(httpClient, meta.RevocationEndpoint, entry.ClientID, entry.secret(), entry.AccessToken, "access_token")
+		}
+		revoked = revokeErr == nil
+	}
+
+	entry.ClearTokens()
+	if err := store.Save(); err != nil {
+		return revoked, fmt.Errorf("failed to save auth store: %w", err)
+	}
+
+	if revokeErr != nil {
+		return false, &RevocationError{Err: revokeErr}
+	}
+	return revoked, nil
+}
+
+// revokeToken sends an RFC 7009 token revocation request.
+func revokeToken(httpClient *http.Client, revocationEndpoint, clientID, clientSecret, token, tokenTypeHint string) error {
+	data := url.Values{
+		"token":           {token},
+		"token_type_hint": {tokenTypeHint},
+	}
+	setClient(data, clientID, clientSecret)
+
+	resp, err := httpClient.Post(revocationEndpoint, "application/x-www-form-urlencoded", bytes.NewBufferString(data.Encode()))
+	if err != nil {
+		return fmt.Errorf("revocation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The server responds 200 even for unknown tokens
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("revocation endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// revocationServer serves authorization server metadata with a revocation
+// endpoint and records the revoked tokens.
+func revocationServer(t *testing.T, status int, revoked *[]string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			json.NewEncoder(w).Encode(ServerMetadata{
+				AuthorizationEndpoint: server.URL + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+				RevocationEndpoint:    server.URL + "/revoke",
+			})
+		case "/revoke":
+			r.ParseForm()
+			*revoked = append(*revoked, r.PostForm.Get("token_type_hint")+":"+r.PostForm.Get("token"))
+			w.WriteHeader(status)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func saveTestEntry(t *testing.T, serverURL string) {
+	t.Helper()
+	store := &AuthStore{Entries: map[string]*AuthEntry{
+		serverURL: {
+			ClientID:     "client",
+			AccessToken:  "access",
+			RefreshToken: "refresh",
+			ExpiresAt:    time.Now().Add(time.Hour),
+		},
+	}}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+}
+
+func TestLogout_RevokesAndKeepsClient(t *testing.T) {
+	setTestStateHome(t)
+	var revoked []string
+	server := revocationServer(t, http.StatusOK, &revoked)
+	defer server.Close()
+	saveTestEntry(t, server.URL)
+
+	ok, err := Logout(http.DefaultClient, server.URL, "", "")
+	if err != nil {
+		t.Fatalf("Logout() error: %v", err)
+	}
+	if !ok {
+		t.Error("Logout() revoked = false, want true")
+	}
+	if len(revoked) != 2 || revoked[0] != "refresh_token:refresh" || revoked[1] != "access_token:access" {
+		t.Errorf("revoked = %v, want refresh then access token", revoked)
+	}
+
+	loaded, err := LoadStore()
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	entry := loaded.Entries[server.URL]
+	if entry == nil || entry.ClientID != "client" {
+		t.Fatalf("client registration should be kept, got %+v", entry)
+	}
+	if entry.AccessToken != "" || entry.RefreshToken != "" {
+		t.Errorf("tokens should be removed, got %+v", entry)
+	}
+}
+
+func TestLogout_RevocationFailureStillClearsTokens(t *testing.T) {
+	setTestStateHome(t)
+	var revoked []string
+	server := revocationServer(t, http.StatusServiceUnavailable, &revoked)
+	defer server.Close()
+	saveTestEntry(t, server.URL)
+
+	ok, err := Logout(http.DefaultClient, server.URL, "", "")
+	var revocationErr *RevocationError
+	if !errors.As(err, &revocationErr) {
+		t.Fatalf("Logout() error = %v, want RevocationError", err)
+	}
+	if ok {
+		t.Error("Logout() revoked = true, want false")
+	}
+
+	if _, err := GetValidToken(http.DefaultClient, server.URL, "", ""); err == nil {
+		t.Error("GetValidToken() should fail after logout")
+	}
+}
+
+func TestRefresh_ForcesNewToken(t *testing.T) {
+	setTestStateHome(t)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			json.NewEncoder(w).Encode(ServerMetadata{
+				AuthorizationEndpoint: server.URL + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+			})
+		case "/token":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "new-access",
+				"refresh_token": "new-refresh",
+				"expires_in":    3600,
+			})
+		}
+	}))
+	defer server.Close()
+	saveTestEntry(t, server.URL)
+
+	entry, err := Refresh(http.DefaultClient, server.URL, "", "")
+	if err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+	if entry.AccessToken != "new-access" || entry.RefreshToken != "new-refresh" {
+		t.Errorf("entry = %+v, want rotated tokens", entry)
+	}
+}
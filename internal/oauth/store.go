@@ -2,21 +2,59 @@ package oauth
 
 import (
 	"encoding/json"
-	"os"
+	"fmt"
+	"net/url"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/juanibiapina/mcpli/internal/config"
 )
 
 // AuthEntry holds OAuth credentials for a single server.
 type AuthEntry struct {
-	ClientID            string `json:"client_id"`
-	ClientSecret        string `json:"client_secret,omitempty"`
-	AccessToken         string `json:"access_token"`
-	RefreshToken        string `json:"refresh_token,omitempty"`
-	ExpiresAt           time.Time `json:"expires_at"`
-	TokenType           string `json:"token_type"`
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	TokenType    string    `json:"token_type"`
+	Scope        string    `json:"scope,omitempty"`
+	Resource     string    `json:"resource,omitempty"`
+	GrantType    string    `json:"grant_type,omitempty"`
+	RedirectURI  string    `json:"redirect_uri,omitempty"`
+	Issuer       string    `json:"issuer,omitempty"`
+	// ExpandSecret is set when ClientSecret was given by the user, whose
+	// ${VAR} references are expanded when it is sent. Secrets issued by
+	// client registration are sent as is.
+	ExpandSecret bool `json:"expand_client_secret,omitempty"`
+}
+
+// secret returns the client secret to send.
+func (e *AuthEntry) secret() string {
+	if e.ExpandSecret {
+		return config.ExpandEnv(e.ClientSecret)
+	}
+	return e.ClientSecret
+}
+
+// ClearTokens removes the tokens of an entry, keeping the client registration.
+func (e *AuthEntry) ClearTokens() {
+	e.AccessToken = ""
+	e.RefreshToken = ""
+	e.ExpiresAt = time.Time{}
+	e.TokenType = ""
+}
+
+// registeredRedirectURI returns the redirect URI the client was registered
+// with. Entries from before dynamic callback ports used the fixed port.
+func (e *AuthEntry) registeredRedirectURI() string {
+	if e.RedirectURI == "" && e.GrantType == "" {
+		return fmt.Sprintf("http://127.0.0.1:%d%s", CallbackPort, CallbackPath)
+	}
+	return e.RedirectURI
 }
 
 // IsExpired returns true if the access token has expired (with a 30-second buffer).
@@ -24,47 +62,55 @@ func (e *AuthEntry) IsExpired() bool {
 	return time.Now().After(e.ExpiresAt.Add(-30 * time.Second))
 }
 
-// AuthStore holds OAuth state for all servers.
+// AuthStore holds OAuth state for all servers. Entries are keyed by
+// EntryKey, so a server can hold credentials for several identities and
+// authorization servers.
 type AuthStore struct {
 	Entries map[string]*AuthEntry `json:"entries"`
+	// Metadata caches authorization server metadata by normalized server URL.
+	Metadata map[string]*CachedMetadata `json:"metadata,omitempty"`
+
+	// backend is where the store was loaded from and is saved to.
+	backend Backend
 }
 
-// storePath returns the path to the auth store file.
+// storePath returns the path to the plaintext auth store file.
 func storePath() string {
 	return filepath.Join(xdg.StateHome, "mcpli", "auth.json")
 }
 
-// LoadStore reads the auth store from disk.
-// Returns an empty store if the file doesn't exist.
+// LoadStore reads the auth store from the configured backend.
+// Returns an empty store if nothing has been stored yet.
 func LoadStore() (*AuthStore, error) {
-	path := storePath()
+	return loadStoreFrom(defaultBackend)
+}
 
-	data, err := os.ReadFile(path)
+func loadStoreFrom(backend Backend) (*AuthStore, error) {
+	data, err := backend.Load()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &AuthStore{Entries: make(map[string]*AuthEntry)}, nil
-		}
 		return nil, err
 	}
 
-	var store AuthStore
-	if err := json.Unmarshal(data, &store); err != nil {
-		return nil, err
+	store := AuthStore{backend: backend}
+	if data != nil {
+		if err := json.Unmarshal(data, &store); err != nil {
+			return nil, err
+		}
 	}
 
 	if store.Entries == nil {
 		store.Entries = make(map[string]*AuthEntry)
 	}
+	store.normalizeKeys()
 
 	return &store, nil
 }
 
-// Save writes the auth store to disk with 0600 permissions.
+// Save writes the auth store to the backend it was loaded from.
 func (s *AuthStore) Save() error {
-	path := storePath()
-
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+	backend := s.backend
+	if backend == nil {
+		backend = defaultBackend
 	}
 
 	data, err := json.MarshalIndent(s, "", "  ")
@@ -72,10 +118,128 @@ func (s *AuthStore) Save() error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0600)
+	return backend.Save(data)
+}
+
+// NormalizeURL returns the canonical form of a server URL, used to key
+// credentials and as the RFC 8707 resource indicator: lowercase scheme and
+// host, without default port, trailing slash or fragment.
+func NormalizeURL(serverURL string) string {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return serverURL
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	if port := parsed.Port(); (parsed.Scheme == "https" && port == "443") || (parsed.Scheme == "http" && port == "80") {
+		parsed.Host = strings.TrimSuffix(parsed.Host, ":"+port)
+	}
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = ""
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String()
+}
+
+// EntryKey returns the auth store key of the credentials for a server URL,
+// identity and issuer: "<issuer> <url>#<identity>". The default identity is
+// the empty string; entries without a known issuer are keyed by URL and
+// identity only. Neither issuers nor normalized URLs contain spaces or "#",
+// so keys split unambiguously.
+func EntryKey(serverURL, identity, issuer string) string {
+	key := NormalizeURL(serverURL)
+	if issuer != "" {
+		key = issuer + " " + key
+	}
+	if identity != "" {
+		key += "#" + identity
+	}
+	return key
+}
+
+// splitKey returns the server URL, identity and issuer of an auth store key.
+func splitKey(key string) (serverURL, identity, issuer string) {
+	rest, identity, _ := strings.Cut(key, "#")
+	issuer, serverURL, found := strings.Cut(rest, " ")
+	if !found {
+		return issuer, identity, ""
+	}
+	return serverURL, identity, issuer
+}
+
+// normalizeKeys rewrites entries stored by older versions under raw server
+// URLs, or without their issuer, to their current keys. An existing entry
+// under the current key wins.
+func (s *AuthStore) normalizeKeys() {
+	for key, entry := range s.Entries {
+		serverURL, identity, issuer := splitKey(key)
+		if issuer == "" {
+			issuer = entry.Issuer
+		}
+		normalized := EntryKey(serverURL, identity, issuer)
+		if normalized == key {
+			continue
+		}
+		if _, exists := s.Entries[normalized]; !exists {
+			s.Entries[normalized] = entry
+		}
+		delete(s.Entries, key)
+	}
+}
+
+// entryKey returns the key of the credentials for a server URL, identity and
+// issuer. Without an issuer (a server that has not pinned one yet), the
+// credentials of the identity are used if there are some for a single
+// issuer only.
+func (s *AuthStore) entryKey(serverURL, identity, issuer string) string {
+	key := EntryKey(serverURL, identity, issuer)
+	if _, exists := s.Entries[key]; exists || issuer != "" {
+		return key
+	}
+
+	normalized := NormalizeURL(serverURL)
+	var matches []string
+	for k := range s.Entries {
+		if u, id, _ := splitKey(k); u == normalized && id == identity {
+			matches = append(matches, k)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0]
+	}
+	return key
+}
+
+// Entry returns the credentials for a server URL, identity and issuer, or
+// nil. An empty issuer matches the credentials of a single issuer.
+func (s *AuthStore) Entry(serverURL, identity, issuer string) *AuthEntry {
+	return s.Entries[s.entryKey(serverURL, identity, issuer)]
+}
+
+// SetEntry stores the credentials for a server URL and identity, under the
+// issuer of the entry.
+func (s *AuthStore) SetEntry(serverURL, identity string, entry *AuthEntry) {
+	s.Entries[EntryKey(serverURL, identity, entry.Issuer)] = entry
+}
+
+// Identities returns the sorted identities with credentials for a server URL,
+// from any issuer. The default identity is returned as the empty string.
+func (s *AuthStore) Identities(serverURL string) []string {
+	normalized := NormalizeURL(serverURL)
+	seen := make(map[string]bool)
+	var identities []string
+	for key := range s.Entries {
+		if u, identity, _ := splitKey(key); u == normalized && !seen[identity] {
+			seen[identity] = true
+			identities = append(identities, identity)
+		}
+	}
+	sort.Strings(identities)
+	return identities
 }
 
-// Delete removes the auth entry for the given server URL.
-func (s *AuthStore) Delete(serverURL string) {
-	delete(s.Entries, serverURL)
+// Delete removes the auth entry for the given server URL, identity and
+// issuer.
+func (s *AuthStore) Delete(serverURL, identity, issuer string) {
+	delete(s.Entries, s.entryKey(serverURL, identity, issuer))
 }
@@ -89,7 +89,7 @@ func TestAuthStore_Delete(t *testing.T) {
 		t.Fatalf("Save() error: %v", err)
 	}
 
-	store.Delete("https://a.com/mcp")
+	store.Delete("https://a.com/mcp", "", "")
 	if err := store.Save(); err != nil {
 		t.Fatalf("Save() error: %v", err)
 	}
@@ -125,3 +125,147 @@ func TestAuthEntry_IsExpired(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthEntry_RegisteredRedirectURI(t *testing.T) {
+	legacy := &AuthEntry{ClientID: "old"}
+	if got, want := legacy.registeredRedirectURI(), "http://127.0.0.1:19877/oauth/callback"; got != want {
+		t.Errorf("legacy registeredRedirectURI() = %q, want %q", got, want)
+	}
+
+	device := &AuthEntry{ClientID: "dev", GrantType: deviceCodeGrantType}
+	if got := device.registeredRedirectURI(); got != "" {
+		t.Errorf("device registeredRedirectURI() = %q, want empty", got)
+	}
+
+	current := &AuthEntry{ClientID: "new", RedirectURI: "http://[::1]:5000/oauth/callback"}
+	if got := current.registeredRedirectURI(); got != current.RedirectURI {
+		t.Errorf("registeredRedirectURI() = %q, want %q", got, current.RedirectURI)
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://example.com/mcp", "https://example.com/mcp"},
+		{"https://example.com/mcp/", "https://example.com/mcp"},
+		{"HTTPS://Example.COM/mcp", "https://example.com/mcp"},
+		{"https://example.com:443/mcp", "https://example.com/mcp"},
+		{"http://example.com:80/mcp", "http://example.com/mcp"},
+		{"http://localhost:8080/mcp/", "http://localhost:8080/mcp"},
+		{"https://example.com/mcp#frag", "https://example.com/mcp"},
+		{"https://example.com/", "https://example.com"},
+		{"https://example.com/mcp?tenant=a", "https://example.com/mcp?tenant=a"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.input); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestAuthStore_Identities(t *testing.T) {
+	setTestStateHome(t)
+
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+	store.SetEntry("https://example.com/mcp/", "", &AuthEntry{AccessToken: "default"})
+	store.SetEntry("https://example.com/mcp", "personal", &AuthEntry{AccessToken: "personal"})
+	store.SetEntry("https://other.com/mcp", "work", &AuthEntry{AccessToken: "other"})
+
+	if got := store.Entry("https://EXAMPLE.com/mcp", "", "").AccessToken; got != "default" {
+		t.Errorf("default identity token = %q, want %q", got, "default")
+	}
+	if got := store.Entry("https://example.com/mcp/", "personal", "").AccessToken; got != "personal" {
+		t.Errorf("personal identity token = %q, want %q", got, "personal")
+	}
+	if store.Entry("https://example.com/mcp", "work", "") != nil {
+		t.Error("expected no entry for identity of another server")
+	}
+
+	identities := store.Identities("https://example.com/mcp/")
+	if len(identities) != 2 || identities[0] != "" || identities[1] != "personal" {
+		t.Errorf("Identities() = %q, want [\"\" \"personal\"]", identities)
+	}
+}
+
+func TestAuthStore_Issuers(t *testing.T) {
+	store := &AuthStore{Entries: make(map[string]*AuthEntry)}
+	store.SetEntry("https://example.com/mcp", "", &AuthEntry{AccessToken: "a", Issuer: "https://a.example.com"})
+
+	// An unpinned server uses the credentials of its only issuer
+	if entry := store.Entry("https://example.com/mcp", "", ""); entry == nil || entry.AccessToken != "a" {
+		t.Errorf("Entry() without issuer = %+v, want a", entry)
+	}
+
+	store.SetEntry("https://example.com/mcp/", "", &AuthEntry{AccessToken: "b", Issuer: "https://b.example.com"})
+	if len(store.Entries) != 2 {
+		t.Fatalf("entries = %v, want one per issuer", store.Entries)
+	}
+	if entry := store.Entry("https://example.com/mcp", "", "https://b.example.com"); entry == nil || entry.AccessToken != "b" {
+		t.Errorf("Entry() for issuer b = %+v", entry)
+	}
+	if entry := store.Entry("https://example.com/mcp", "", ""); entry != nil {
+		t.Errorf("Entry() without issuer = %+v, want nil when two issuers match", entry)
+	}
+	if got := store.Identities("https://example.com/mcp"); len(got) != 1 || got[0] != "" {
+		t.Errorf("Identities() = %q, want the default identity once", got)
+	}
+
+	store.Delete("https://example.com/mcp", "", "https://a.example.com")
+	if _, ok := store.Entries["https://b.example.com https://example.com/mcp"]; !ok || len(store.Entries) != 1 {
+		t.Errorf("entries after Delete = %v", store.Entries)
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		key, url, identity, issuer string
+	}{
+		{"https://example.com/mcp", "https://example.com/mcp", "", ""},
+		{"https://example.com/mcp#my account", "https://example.com/mcp", "my account", ""},
+		{"https://auth.example.com https://example.com/mcp#work", "https://example.com/mcp", "work", "https://auth.example.com"},
+	}
+	for _, tt := range tests {
+		u, identity, issuer := splitKey(tt.key)
+		if u != tt.url || identity != tt.identity || issuer != tt.issuer {
+			t.Errorf("splitKey(%q) = %q, %q, %q", tt.key, u, identity, issuer)
+		}
+		if got := EntryKey(u, identity, issuer); got != tt.key {
+			t.Errorf("EntryKey(%q, %q, %q) = %q, want %q", u, identity, issuer, got, tt.key)
+		}
+	}
+}
+
+func TestLoadStore_NormalizesLegacyKeys(t *testing.T) {
+	setTestStateHome(t)
+
+	legacy := `{"entries": {
+		"https://example.com/mcp/": {"client_id": "legacy", "access_token": "old"},
+		"https://both.com/mcp/": {"client_id": "legacy", "access_token": "old"},
+		"https://both.com/mcp": {"client_id": "current", "access_token": "new"},
+		"https://issued.com/mcp#work": {"client_id": "issued", "access_token": "x", "issuer": "https://auth.issued.com"}
+	}}`
+	if err := (FileBackend{}).Save([]byte(legacy)); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	store, err := LoadStore()
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+
+	if len(store.Entries) != 3 {
+		t.Errorf("expected 3 entries, got %d: %v", len(store.Entries), store.Entries)
+	}
+	if entry := store.Entries["https://example.com/mcp"]; entry == nil || entry.ClientID != "legacy" {
+		t.Errorf("legacy entry not moved to normalized key: %+v", entry)
+	}
+	if entry := store.Entries["https://both.com/mcp"]; entry == nil || entry.ClientID != "current" {
+		t.Errorf("normalized entry should win over legacy key: %+v", entry)
+	}
+	if entry := store.Entries["https://auth.issued.com https://issued.com/mcp#work"]; entry == nil || entry.ClientID != "issued" {
+		t.Errorf("entry not moved under its issuer: %+v", entry)
+	}
+}
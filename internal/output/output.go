@@ -0,0 +1,271 @@
+// Package output writes JSON values as JSON, YAML, text, tables or
+// newline-delimited JSON.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats.
+const (
+	JSON   = "json"
+	YAML   = "yaml"
+	Text   = "text"
+	Table  = "table"
+	NDJSON = "ndjson"
+	Raw    = "raw"
+)
+
+// Formats lists the output formats.
+var Formats = []string{JSON, YAML, Text, Table, NDJSON, Raw}
+
+// maxCellWidth bounds the width of table cells.
+const maxCellWidth = 60
+
+// identifyingColumns are written first in tables, in this order.
+var identifyingColumns = []string{"id", "name", "key", "title", "uri"}
+
+// Check returns an error for an unknown format.
+func Check(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (use %s)", format, strings.Join(Formats, ", "))
+}
+
+// Write writes decoded JSON values in a format:
+//
+//   - json: indented JSON
+//   - yaml: YAML, one document per value
+//   - text: strings without quotes, arrays one item per line, objects as YAML
+//   - table: arrays of objects as rows with a column per key, objects as
+//     key/value rows
+//   - ndjson: one compact JSON value per line, with arrays split into their
+//     items
+//   - raw: strings without quotes, anything else as compact JSON
+func Write(w io.Writer, format string, values []interface{}) error {
+	switch format {
+	case JSON:
+		for _, v := range values {
+			data, err := marshal(v, "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, string(data))
+		}
+	case NDJSON:
+		for _, v := range values {
+			items := []interface{}{v}
+			if arr, ok := v.([]interface{}); ok {
+				items = arr
+			}
+			for _, item := range items {
+				data, err := marshal(item, "")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, string(data))
+			}
+		}
+	case Raw:
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				fmt.Fprintln(w, s)
+				continue
+			}
+			data, err := marshal(v, "")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, string(data))
+		}
+	case YAML:
+		for i, v := range values {
+			if i > 0 {
+				fmt.Fprintln(w, "---")
+			}
+			if err := writeYAML(w, v); err != nil {
+				return err
+			}
+		}
+	case Text:
+		for _, v := range values {
+			if err := writeText(w, v); err != nil {
+				return err
+			}
+		}
+	case Table:
+		return writeTable(w, values)
+	default:
+		return Check(format)
+	}
+	return nil
+}
+
+// marshal encodes a value as JSON without escaping HTML characters.
+func marshal(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// writeYAML writes a value as a YAML document, with numbers as written in
+// the JSON and mapping keys in sorted order.
+func writeYAML(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(v)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlNode converts a decoded JSON value to a YAML node, keeping the text of
+// numbers decoded as json.Number.
+func yamlNode(v interface{}) *yaml.Node {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range slices.Sorted(maps.Keys(x)) {
+			node.Content = append(node.Content, yamlNode(k), yamlNode(x[k]))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range x {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: x}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(x)}
+	}
+	// Numbers, written as JSON so that the encoder resolves their tag
+	data, _ := marshal(v, "")
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(data)}
+}
+
+func writeText(w io.Writer, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+	case string:
+		fmt.Fprintln(w, x)
+	case []interface{}:
+		for _, item := range x {
+			if err := writeText(w, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		return writeYAML(w, x)
+	default:
+		data, _ := marshal(x, "")
+		fmt.Fprintln(w, string(data))
+	}
+	return nil
+}
+
+// writeTable writes objects as rows with a column per key, identifying
+// columns such as name first and the others in sorted order, and a single
+// object as key/value rows. Anything else is written
+// in a single VALUE column.
+func writeTable(w io.Writer, values []interface{}) error {
+	rows := values
+	if len(values) == 1 {
+		switch x := values[0].(type) {
+		case []interface{}:
+			rows = x
+		case map[string]interface{}:
+			rows = nil
+			for _, k := range slices.Sorted(maps.Keys(x)) {
+				rows = append(rows, map[string]interface{}{"key": k, "value": x[k]})
+			}
+			return writeRows(w, []string{"key", "value"}, rows)
+		}
+	}
+
+	columns := map[string]bool{}
+	for _, row := range rows {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			return writeRows(w, nil, rows)
+		}
+		for k := range obj {
+			columns[k] = true
+		}
+	}
+	var names []string
+	for _, k := range identifyingColumns {
+		if columns[k] {
+			names = append(names, k)
+			delete(columns, k)
+		}
+	}
+	return writeRows(w, append(names, slices.Sorted(maps.Keys(columns))...), rows)
+}
+
+// writeRows writes rows of objects in the given columns, or rows of single
+// values if there are no columns.
+func writeRows(w io.Writer, columns []string, rows []interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if columns == nil {
+		fmt.Fprintln(tw, "VALUE")
+		for _, row := range rows {
+			fmt.Fprintln(tw, cell(row))
+		}
+		return tw.Flush()
+	}
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		obj := row.(map[string]interface{})
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = cell(obj[c])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// cell formats a value for a table cell on one line, shortened to
+// maxCellWidth.
+func cell(v interface{}) string {
+	var s string
+	switch x := v.(type) {
+	case nil:
+	case string:
+		s = x
+	default:
+		data, _ := marshal(x, "")
+		s = string(data)
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxCellWidth {
+		s = string(runes[:maxCellWidth-3]) + "..."
+	}
+	return s
+}
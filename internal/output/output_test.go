@@ -0,0 +1,125 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func write(t *testing.T, format string, docs ...string) string {
+	t.Helper()
+	values := make([]interface{}, len(docs))
+	for i, d := range docs {
+		values[i] = decode(t, d)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, format, values); err != nil {
+		t.Fatalf("Write(%s) error = %v", format, err)
+	}
+	return buf.String()
+}
+
+const products = `[
+	{"name": "Milk", "price": 1.5, "tags": ["dairy"]},
+	{"name": "Oat <milk>", "price": 12345678901234567}
+]`
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format string
+		docs   []string
+		want   string
+	}{
+		{JSON, []string{`{"a": "<b>"}`}, "{\n  \"a\": \"<b>\"\n}\n"},
+		{NDJSON, []string{products, `"x"`}, `{"name":"Milk","price":1.5,"tags":["dairy"]}` + "\n" +
+			`{"name":"Oat <milk>","price":12345678901234567}` + "\n\"x\"\n"},
+		{Raw, []string{`"a\nb"`, `{"a": 1}`, `null`}, "a\nb\n{\"a\":1}\nnull\n"},
+		{Text, []string{`["a", 1, null, {"b": true}]`}, "a\n1\nb: true\n"},
+		{Table, []string{products}, "" +
+			"NAME        PRICE              TAGS\n" +
+			"Milk        1.5                [\"dairy\"]\n" +
+			"Oat <milk>  12345678901234567  \n"},
+		{Table, []string{`{"b": "two\nlines", "a": null}`}, "" +
+			"KEY  VALUE\n" +
+			"a    \n" +
+			"b    two lines\n"},
+		{Table, []string{`"x"`, `2`}, "VALUE\nx\n2\n"},
+		{Table, []string{`[{"description": "Find", "name": "search", "id": 1}]`}, "" +
+			"ID  NAME    DESCRIPTION\n" +
+			"1   search  Find\n"},
+	}
+	for _, tt := range tests {
+		if got := write(t, tt.format, tt.docs...); got != tt.want {
+			t.Errorf("Write(%s, %v) =\n%s\nwant\n%s", tt.format, tt.docs, got, tt.want)
+		}
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	got := write(t, YAML, `{
+		"name": "Milk",
+		"count": 2,
+		"big": 12345678901234567890,
+		"flags": {"yes": "yes", "empty": "", "num": "12", "colon": "a: b"},
+		"items": [{"id": 1, "tags": ["a", "b"]}, [], {}],
+		"notes": "first\nsecond\n",
+		"trimmed": "one\ntwo",
+		"none": null
+	}`, `"second"`)
+	want := `big: 12345678901234567890
+count: 2
+flags:
+  colon: 'a: b'
+  empty: ""
+  num: "12"
+  yes: yes
+items:
+  - id: 1
+    tags:
+      - a
+      - b
+  - []
+  - {}
+name: Milk
+none: null
+notes: |
+  first
+  second
+trimmed: |-
+  one
+  two
+---
+second
+`
+	if got != want {
+		t.Errorf("YAML =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCellTruncation(t *testing.T) {
+	got := cell(strings.Repeat("x", 100))
+	if len(got) != maxCellWidth || !strings.HasSuffix(got, "...") {
+		t.Errorf("cell() = %q", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check("table"); err != nil {
+		t.Errorf("Check(table) error = %v", err)
+	}
+	if err := Check("xml"); err == nil {
+		t.Error("Check(xml) succeeded")
+	}
+}
@@ -0,0 +1,297 @@
+// Package prompt asks for tool arguments interactively, walking the tool's
+// input schema.
+package prompt
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juanibiapina/mcpli/internal/schema"
+	"github.com/juanibiapina/mcpli/internal/terminal"
+	"github.com/juanibiapina/mcpli/internal/toolargs"
+)
+
+// maxDepth is how many levels of nested objects and arrays are asked for;
+// deeper properties are skipped.
+const maxDepth = 8
+
+// ErrInputEnded is returned when the input ends before all values are read.
+var ErrInputEnded = errors.New("input ended")
+
+// Prompter reads answers from in and writes questions to out.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// New creates a prompter.
+func New(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Arguments asks for the properties of an object schema, required ones
+// first. Properties present in args are kept without asking, unless all is
+// set, in which case every property is asked with its current value as the
+// default. Pressing Enter accepts the default, or skips an optional
+// property.
+func (p *Prompter) Arguments(schema json.RawMessage, args map[string]interface{}, all bool) (map[string]interface{}, error) {
+	var root map[string]interface{}
+	if len(schema) > 0 {
+		if err := json.Unmarshal(schema, &root); err != nil {
+			return nil, fmt.Errorf("invalid input schema: %w", err)
+		}
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	if err := p.object(root, args, all, "", 0); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// Confirm asks a yes/no question that defaults to yes.
+func (p *Prompter) Confirm(question string) (bool, error) {
+	return p.confirm(question, true)
+}
+
+// object asks for the properties of an object schema, filling values.
+func (p *Prompter) object(schema, values map[string]interface{}, all bool, indent string, depth int) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		current, has := values[name]
+		if has && !all {
+			continue
+		}
+		prop, _ := properties[name].(map[string]interface{})
+		value, set, err := p.value(name, prop, required[name], current, has, indent, depth)
+		if err != nil {
+			return err
+		}
+		if set {
+			values[name] = value
+		}
+	}
+	return nil
+}
+
+// value asks for the value of one property. It returns false if an optional
+// property was skipped.
+func (p *Prompter) value(label string, s map[string]interface{}, required bool, current interface{}, hasCurrent bool, indent string, depth int) (interface{}, bool, error) {
+	if depth > maxDepth {
+		return nil, false, nil
+	}
+	def, hasDef := current, hasCurrent
+	if !hasDef {
+		def, hasDef = s["default"]
+	}
+	typ := schema.Type(s)
+
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		return p.choice(label, s, enum, required, def, hasDef, indent)
+	}
+
+	switch typ {
+	case "object":
+		if _, ok := s["properties"].(map[string]interface{}); !ok {
+			break
+		}
+		p.header(label, s, required, indent)
+		if !required {
+			set, err := p.confirm(indent+"  Set "+label+"?", hasCurrent)
+			if err != nil || !set {
+				return nil, false, err
+			}
+		}
+		nested, _ := current.(map[string]interface{})
+		if nested == nil {
+			nested = map[string]interface{}{}
+		}
+		err := p.object(s, nested, true, indent+"  ", depth+1)
+		return nested, true, err
+
+	case "array":
+		return p.array(label, s, required, def, hasDef, indent, depth)
+	}
+
+	p.header(label, s, required, indent)
+	for {
+		line, err := p.ask(indent, def, hasDef)
+		if err != nil {
+			return nil, false, err
+		}
+		if line == "" {
+			if hasDef {
+				return def, true, nil
+			}
+			if required {
+				fmt.Fprintf(p.out, "%s  a value is required\n", indent)
+				continue
+			}
+			return nil, false, nil
+		}
+		value, err := toolargs.Coerce(typ, line)
+		if err != nil {
+			fmt.Fprintf(p.out, "%s  %v\n", indent, err)
+			continue
+		}
+		return value, true, nil
+	}
+}
+
+// array asks for array items one at a time until an empty answer.
+func (p *Prompter) array(label string, schema map[string]interface{}, required bool, def interface{}, hasDef bool, indent string, depth int) (interface{}, bool, error) {
+	p.header(label, schema, required, indent)
+	if hasDef {
+		data, _ := json.Marshal(def)
+		keep, err := p.confirm(fmt.Sprintf("%s  Keep %s?", indent, data), true)
+		if err != nil {
+			return nil, false, err
+		}
+		if keep {
+			return def, true, nil
+		}
+	}
+
+	items, _ := schema["items"].(map[string]interface{})
+	fmt.Fprintf(p.out, "%s  Enter one item at a time; an empty answer ends the list\n", indent)
+	values := []interface{}{}
+	for i := 0; ; i++ {
+		value, set, err := p.value(fmt.Sprintf("%s[%d]", label, i), items, false, nil, false, indent+"  ", depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		if !set {
+			break
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 && !required {
+		return nil, false, nil
+	}
+	return values, true, nil
+}
+
+// choice asks to pick one of the enum values, by number or value.
+func (p *Prompter) choice(label string, schema map[string]interface{}, enum []interface{}, required bool, def interface{}, hasDef bool, indent string) (interface{}, bool, error) {
+	p.header(label, schema, required, indent)
+	for i, v := range enum {
+		fmt.Fprintf(p.out, "%s  %d) %s\n", indent, i+1, formatValue(v))
+	}
+
+	for {
+		line, err := p.ask(indent, def, hasDef)
+		if err != nil {
+			return nil, false, err
+		}
+		if line == "" {
+			if hasDef {
+				return def, true, nil
+			}
+			if required {
+				fmt.Fprintf(p.out, "%s  pick one of the values\n", indent)
+				continue
+			}
+			return nil, false, nil
+		}
+		if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(enum) {
+			return enum[n-1], true, nil
+		}
+		for _, v := range enum {
+			if formatValue(v) == line || fmt.Sprint(v) == line {
+				return v, true, nil
+			}
+		}
+		fmt.Fprintf(p.out, "%s  pick a number from 1 to %d\n", indent, len(enum))
+	}
+}
+
+// header prints the name, type and description of a property.
+func (p *Prompter) header(label string, s map[string]interface{}, required bool, indent string) {
+	details := schema.Type(s)
+	if details == "array" {
+		if items, ok := s["items"].(map[string]interface{}); ok && schema.Type(items) != "" {
+			details = "array of " + schema.Type(items)
+		}
+	}
+	if required {
+		details = strings.TrimPrefix(details+", required", ", ")
+	}
+
+	line := label
+	if details != "" {
+		line += " (" + details + ")"
+	}
+	if desc, _ := s["description"].(string); desc != "" {
+		line += ": " + strings.Join(strings.Fields(desc), " ")
+	}
+	fmt.Fprintf(p.out, "%s%s\n", indent, line)
+}
+
+// ask prints the input prompt, with the default if there is one, and reads
+// a line.
+func (p *Prompter) ask(indent string, def interface{}, hasDef bool) (string, error) {
+	if hasDef {
+		fmt.Fprintf(p.out, "%s  [%s]> ", indent, formatValue(def))
+	} else {
+		fmt.Fprintf(p.out, "%s  > ", indent)
+	}
+	return p.readLine()
+}
+
+// confirm asks a yes/no question.
+func (p *Prompter) confirm(question string, def bool) (bool, error) {
+	ok, err := terminal.YesNo(p.in, p.out, question, def)
+	if err == io.EOF {
+		return false, ErrInputEnded
+	}
+	return ok, err
+}
+
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			fmt.Fprintln(p.out)
+			return "", ErrInputEnded
+		}
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// formatValue formats a value for display: strings as is, anything else as
+// JSON.
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,131 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const inputSchema = `{
+	"type": "object",
+	"properties": {
+		"query": {"type": "string", "description": "Search text"},
+		"limit": {"type": "integer", "default": 10},
+		"sort": {"type": "string", "enum": ["price", "name"]},
+		"exact": {"type": "boolean"},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"filter": {
+			"type": "object",
+			"properties": {
+				"min": {"type": "number"},
+				"max": {"type": "number"}
+			},
+			"required": ["min"]
+		}
+	},
+	"required": ["query", "sort"]
+}`
+
+func fill(t *testing.T, input string, args map[string]interface{}, all bool) (map[string]interface{}, string) {
+	t.Helper()
+	var out strings.Builder
+	p := New(strings.NewReader(input), &out)
+	values, err := p.Arguments(json.RawMessage(inputSchema), args, all)
+	if err != nil {
+		t.Fatalf("Arguments() error = %v\noutput:\n%s", err, out.String())
+	}
+	return values, out.String()
+}
+
+func TestArguments(t *testing.T) {
+	// Order: query, sort (required), then exact, filter, limit, tags
+	input := strings.Join([]string{
+		"milk",    // query
+		"2",       // sort: pick by number
+		"y",       // exact
+		"y",       // set filter
+		"1.5",     // filter.min
+		"",        // filter.max: skipped
+		"",        // limit: default
+		"organic", // tags[0]
+		"fresh",   // tags[1]
+		"",        // end of tags
+	}, "\n") + "\n"
+
+	values, out := fill(t, input, nil, false)
+	want := map[string]interface{}{
+		"query":  "milk",
+		"sort":   "name",
+		"exact":  true,
+		"filter": map[string]interface{}{"min": 1.5},
+		"limit":  float64(10),
+		"tags":   []interface{}{"organic", "fresh"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %#v, want %#v", values, want)
+	}
+	for _, s := range []string{"query (string, required): Search text", "1) price", "2) name", "[10]> "} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output does not contain %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestArguments_KeepsGiven(t *testing.T) {
+	// Only sort is missing among the required; optional ones are skipped
+	input := "price\n\nn\n\n\n"
+	values, out := fill(t, input, map[string]interface{}{"query": "milk"}, false)
+
+	if values["query"] != "milk" || values["sort"] != "price" || values["limit"] != float64(10) {
+		t.Errorf("values = %#v", values)
+	}
+	if _, ok := values["filter"]; ok {
+		t.Errorf("filter was set: %#v", values)
+	}
+	if strings.Contains(out, "query (") {
+		t.Errorf("asked for the given query:\n%s", out)
+	}
+}
+
+func TestArguments_AllUsesGivenAsDefaults(t *testing.T) {
+	input := "\n\n\nn\n7\n\n"
+	values, _ := fill(t, input, map[string]interface{}{"query": "milk", "sort": "price"}, true)
+	if values["query"] != "milk" || values["sort"] != "price" || values["limit"] != int64(7) {
+		t.Errorf("values = %#v", values)
+	}
+}
+
+func TestArguments_Reprompts(t *testing.T) {
+	// Required query empty, invalid enum, invalid boolean, invalid integer
+	input := "\nmilk\n9\nprice\nmaybe\nn\nn\nten\n3\n\n"
+	values, out := fill(t, input, nil, false)
+	if values["query"] != "milk" || values["sort"] != "price" || values["exact"] != false || values["limit"] != int64(3) {
+		t.Errorf("values = %#v", values)
+	}
+	for _, s := range []string{"a value is required", "pick a number from 1 to 2", "is not a boolean", `"ten" is not an integer`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output does not contain %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestArguments_InputEnded(t *testing.T) {
+	p := New(strings.NewReader("milk\n"), io.Discard)
+	_, err := p.Arguments(json.RawMessage(inputSchema), nil, false)
+	if !errors.Is(err, ErrInputEnded) {
+		t.Errorf("error = %v, want ErrInputEnded", err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for input, want := range map[string]bool{"\n": true, "y\n": true, "no\n": false, "x\nn\n": false} {
+		p := New(strings.NewReader(input), io.Discard)
+		got, err := p.Confirm("Call?")
+		if err != nil || got != want {
+			t.Errorf("Confirm(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package schema reads the parts of JSON schemas that mcpli needs in more
+// than one place.
+package schema
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Type returns the type of a schema, the first non-null one when it lists
+// several, or "object" when it only declares properties.
+func Type(s map[string]interface{}) string {
+	if t := TypeName(s["type"]); t != "" {
+		return t
+	}
+	if _, ok := s["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// TypeName returns the type named by the value of a "type" keyword; for a
+// list of types, the first one that isn't "null".
+func TypeName(t interface{}) string {
+	switch t := t.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if name, ok := v.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// MissingRequired returns the required top-level properties of a schema that
+// are absent from the arguments, sorted.
+func MissingRequired(schema json.RawMessage, arguments map[string]interface{}) []string {
+	var s struct {
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range s.Required {
+		if _, ok := arguments[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
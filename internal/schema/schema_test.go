@@ -0,0 +1,37 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestType(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`{"type": "string"}`, "string"},
+		{`{"type": ["null", "integer"]}`, "integer"},
+		{`{"type": ["null"]}`, ""},
+		{`{"properties": {"a": {}}}`, "object"},
+		{`{}`, ""},
+	}
+	for _, tt := range tests {
+		var s map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.schema), &s); err != nil {
+			t.Fatal(err)
+		}
+		if got := Type(s); got != tt.want {
+			t.Errorf("Type(%s) = %q, want %q", tt.schema, got, tt.want)
+		}
+	}
+}
+
+func TestMissingRequired(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","required":["b","a","c"]}`)
+	got := MissingRequired(schema, map[string]interface{}{"c": 1})
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingRequired() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package shell
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+)
+
+// Completer completes shell lines on Tab: commands and tool names in the
+// first word, tool names after :help, and the property names of a tool's
+// input schema in its JSON arguments. When several candidates match, the
+// line is extended to their common prefix, and pressing Tab again cycles
+// through them.
+type Completer struct {
+	tools    map[string]config.Tool
+	names    []string
+	commands []string
+
+	// State of the previous completion, for cycling
+	line       string
+	pos        int
+	start      int
+	candidates []string
+	index      int
+}
+
+// NewCompleter creates a completer for the given tools and shell commands.
+func NewCompleter(tools []config.Tool, commands []string) *Completer {
+	c := &Completer{tools: map[string]config.Tool{}, commands: commands}
+	for _, tool := range tools {
+		c.tools[tool.Name] = tool
+		c.names = append(c.names, tool.Name)
+	}
+	sort.Strings(c.names)
+	return c
+}
+
+// Complete has the signature of the AutoCompleteCallback of
+// golang.org/x/term.
+func (c *Completer) Complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		c.candidates = nil
+		return "", 0, false
+	}
+
+	// Tab again after an ambiguous completion: show the next candidate
+	if len(c.candidates) > 1 && line == c.line && pos == c.pos {
+		c.index = (c.index + 1) % len(c.candidates)
+		return c.replace(line, c.start, pos, c.candidates[c.index])
+	}
+
+	start, candidates := c.candidatesAt(line[:pos])
+	c.candidates, c.index = candidates, -1
+	switch len(candidates) {
+	case 0:
+		return "", 0, false
+	case 1:
+		return c.replace(line, start, pos, candidates[0])
+	}
+
+	prefix := commonPrefix(candidates)
+	if len(prefix) <= pos-start {
+		// Nothing to extend: the next Tab cycles through the candidates
+		c.line, c.pos, c.start = line, pos, start
+		return line, pos, true
+	}
+	newLine, newPos, ok := c.replace(line, start, pos, prefix)
+	c.line, c.pos, c.start = newLine, newPos, start
+	return newLine, newPos, ok
+}
+
+// replace puts text in place of line[start:pos] and remembers the result, so
+// the next Tab can cycle from it.
+func (c *Completer) replace(line string, start, pos int, text string) (string, int, bool) {
+	newLine := line[:start] + text + line[pos:]
+	newPos := start + len(text)
+	c.line, c.pos, c.start = newLine, newPos, start
+	return newLine, newPos, true
+}
+
+// candidatesAt returns the start of the word being completed at the end of
+// prefix and the texts that can replace it.
+func (c *Completer) candidatesAt(prefix string) (int, []string) {
+	first := strings.IndexByte(prefix, ' ')
+	if first < 0 {
+		words := append(append([]string{}, c.commands...), c.names...)
+		return 0, withSuffix(matching(words, prefix), " ")
+	}
+
+	command := prefix[:first]
+	rest := prefix[first+1:]
+	argsStart := first + 1
+
+	if command == ":help" {
+		if strings.Contains(rest, " ") {
+			return 0, nil
+		}
+		return argsStart, matching(c.names, rest)
+	}
+
+	tool, ok := c.tools[command]
+	if !ok {
+		return 0, nil
+	}
+	return c.propertyCandidates(tool, prefix, argsStart)
+}
+
+// propertyCandidates completes a property name of the tool's input schema
+// where a JSON object key is expected: at the start of the arguments, or
+// after "{" or ",". Candidates are written as `"name": `.
+func (c *Completer) propertyCandidates(tool config.Tool, prefix string, argsStart int) (int, []string) {
+	args := prefix[argsStart:]
+
+	// Find the word being typed: an open string, or bare characters
+	start := len(args)
+	if open, ok := openString(args); ok {
+		start = open
+	} else {
+		for start > 0 && !strings.ContainsRune(" \t{,", rune(args[start-1])) {
+			start--
+		}
+	}
+	word := strings.TrimPrefix(args[start:], `"`)
+
+	before := strings.TrimRight(args[:start], " \t")
+	opening := ""
+	switch {
+	case before == "" && !strings.HasPrefix(word, "{"):
+		opening = "{"
+	case strings.HasSuffix(before, "{") || strings.HasSuffix(before, ","):
+	default:
+		return 0, nil
+	}
+
+	var candidates []string
+	for _, name := range propertyNames(tool) {
+		if strings.HasPrefix(name, word) && !strings.Contains(args, `"`+name+`"`) {
+			candidates = append(candidates, opening+`"`+name+`": `)
+		}
+	}
+	return argsStart + start, candidates
+}
+
+// openString returns the position of the quote that opens an unterminated
+// JSON string in s.
+func openString(s string) (int, bool) {
+	open, inString, escaped := 0, false, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case inString && escaped:
+			escaped = false
+		case inString && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inString = !inString
+			open = i
+		}
+	}
+	return open, inString
+}
+
+// propertyNames returns the sorted top-level property names of a tool's
+// input schema.
+func propertyNames(tool config.Tool) []string {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	json.Unmarshal(tool.InputSchema, &schema)
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matching(words []string, prefix string) []string {
+	var out []string
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func withSuffix(words []string, suffix string) []string {
+	for i := range words {
+		words[i] += suffix
+	}
+	return words
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
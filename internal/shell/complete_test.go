@@ -0,0 +1,70 @@
+package shell
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+)
+
+func testCompleter() *Completer {
+	return NewCompleter([]config.Tool{
+		{Name: "search_products", InputSchema: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"},"limit":{"type":"integer"},"locale":{"type":"string"}}}`)},
+		{Name: "search_recipes", InputSchema: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}}}`)},
+		{Name: "get_cart"},
+	}, []string{":help", ":quit"})
+}
+
+func complete(c *Completer, line string) string {
+	newLine, _, ok := c.Complete(line, len(line), '\t')
+	if !ok {
+		return line
+	}
+	return newLine
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"get", "get_cart "},
+		{"sea", "search_"},
+		{":h", ":help "},
+		{":help get", ":help get_cart"},
+		{"search_recipes ", `search_recipes {"query": `},
+		{"search_products {", `search_products {"`},
+		{`search_products {"lo`, `search_products {"locale": `},
+		{`search_products {"q`, `search_products {"query": `},
+		{`search_products {"query": "milk", li`, `search_products {"query": "milk", "limit": `},
+		{`search_products {"query": "mi`, `search_products {"query": "mi`},
+		{"unknown {", "unknown {"},
+	}
+	for _, tt := range tests {
+		if got := complete(testCompleter(), tt.line); got != tt.want {
+			t.Errorf("complete(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestComplete_CyclesCandidates(t *testing.T) {
+	c := testCompleter()
+	line := complete(c, `search_products {"query": "x", "l`)
+	if line != `search_products {"query": "x", "l` {
+		t.Fatalf("first Tab = %q, want no change", line)
+	}
+	line = complete(c, line)
+	if line != `search_products {"query": "x", "limit": ` {
+		t.Errorf("second Tab = %q, want limit", line)
+	}
+	line = complete(c, line)
+	if line != `search_products {"query": "x", "locale": ` {
+		t.Errorf("third Tab = %q, want locale", line)
+	}
+}
+
+func TestComplete_OtherKeysIgnored(t *testing.T) {
+	if _, _, ok := testCompleter().Complete("get", 3, 'x'); ok {
+		t.Error("Complete handled a key other than Tab")
+	}
+}
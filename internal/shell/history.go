@@ -0,0 +1,103 @@
+// Package shell implements the line handling of the interactive shell:
+// persistent history, completion from cached tool schemas and variables
+// holding earlier results.
+package shell
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// History is a line history kept in a file, one entry per line. It
+// implements the History interface of golang.org/x/term.
+type History struct {
+	path    string
+	max     int
+	entries []string
+}
+
+// LoadHistory reads the history file at path, keeping the last max entries.
+// A missing file is an empty history.
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{path: path, max: max}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Compact the file once it holds more than twice the kept entries
+	if len(h.entries) > max {
+		compact := len(h.entries) > 2*max
+		h.entries = h.entries[len(h.entries)-max:]
+		if compact {
+			h.save()
+		}
+	}
+	return h, nil
+}
+
+// lineBreaks replaces the line breaks of an entry, which is stored on one
+// line.
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// Add appends an entry to the history and its file. Empty entries and
+// repeats of the latest entry are skipped. The lines of multi-line entries
+// are joined with a space; other whitespace is kept as typed.
+func (h *History) Add(entry string) {
+	entry = strings.TrimSpace(lineBreaks.Replace(entry))
+	if entry == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(entry + "\n")
+}
+
+// Len returns the number of entries.
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At returns an entry; index 0 is the most recent.
+func (h *History) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// save rewrites the history file with the kept entries.
+func (h *History) save() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	data := strings.Join(h.entries, "\n") + "\n"
+	return os.WriteFile(h.path, []byte(data), 0600)
+}
@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistory_PersistsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "srv")
+
+	h, err := LoadHistory(path, 100)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	h.Add("search {}")
+	h.Add("search {}")
+	h.Add("")
+	h.Add(":resources")
+
+	if h.Len() != 2 || h.At(0) != ":resources" || h.At(1) != "search {}" {
+		t.Errorf("entries = %d, At(0) = %q", h.Len(), h.At(0))
+	}
+
+	reloaded, err := LoadHistory(path, 100)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if reloaded.Len() != 2 || reloaded.At(0) != ":resources" {
+		t.Errorf("reloaded %d entries, At(0) = %q", reloaded.Len(), reloaded.At(0))
+	}
+}
+
+func TestHistory_KeepsWhitespace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h, err := LoadHistory(path, 100)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	h.Add(`search {"q": "a  b"}`)
+	h.Add("search {\n  \"q\": \"c\"\n}")
+
+	reloaded, err := LoadHistory(path, 100)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if got := reloaded.At(1); got != `search {"q": "a  b"}` {
+		t.Errorf("At(1) = %q, want the entry as typed", got)
+	}
+	if got := reloaded.At(0); got != `search {   "q": "c" }` {
+		t.Errorf("At(0) = %q, want the lines joined", got)
+	}
+}
+
+func TestHistory_KeepsLastEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, strings.Repeat("x", i+1))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+
+	h, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if h.Len() != 3 || h.At(0) != lines[9] || h.At(2) != lines[7] {
+		t.Errorf("entries = %d, At(0) = %q", h.Len(), h.At(0))
+	}
+
+	data, _ := os.ReadFile(path)
+	if got := strings.Count(string(data), "\n"); got != 3 {
+		t.Errorf("history file has %d lines after compaction, want 3", got)
+	}
+}
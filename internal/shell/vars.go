@@ -0,0 +1,191 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Vars holds the results of earlier tool calls. The n-th result is $n and
+// the latest one is $_. References can select a field with a path, e.g.
+// $1.structuredContent.items[0].id.
+type Vars struct {
+	results []interface{}
+}
+
+// Add stores a result and returns its number.
+func (v *Vars) Add(result json.RawMessage) (int, error) {
+	var value interface{}
+	if err := json.Unmarshal(result, &value); err != nil {
+		return 0, fmt.Errorf("invalid result JSON: %w", err)
+	}
+	v.results = append(v.results, value)
+	return len(v.results), nil
+}
+
+// Len returns the number of stored results.
+func (v *Vars) Len() int {
+	return len(v.results)
+}
+
+// Get returns the value of a reference without the leading "$", e.g. "2",
+// "_" or "_.content[0].text".
+func (v *Vars) Get(ref string) (interface{}, error) {
+	name, path := ref, ""
+	if i := strings.IndexAny(ref, ".["); i >= 0 {
+		name, path = ref[:i], ref[i:]
+	}
+
+	var value interface{}
+	switch n, err := strconv.Atoi(name); {
+	case name == "_":
+		if len(v.results) == 0 {
+			return nil, fmt.Errorf("$_: no results yet")
+		}
+		value = v.results[len(v.results)-1]
+	case err == nil && n >= 1 && n <= len(v.results):
+		value = v.results[n-1]
+	default:
+		return nil, fmt.Errorf("$%s: no such result", name)
+	}
+
+	value, err := Lookup(value, path)
+	if err != nil {
+		return nil, fmt.Errorf("$%s: %w", ref, err)
+	}
+	return value, nil
+}
+
+// Expand replaces the references in input that are outside JSON strings
+// with the JSON encoding of their values, so "$" inside string values is
+// kept as is.
+func (v *Vars) Expand(input string) (string, error) {
+	var out strings.Builder
+	inString, escaped := false, false
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '$':
+			end := referenceEnd(input, i+1)
+			if end == i+1 {
+				break
+			}
+			value, err := v.Get(input[i+1 : end])
+			if err != nil {
+				return "", err
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			out.Write(data)
+			i = end - 1
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), nil
+}
+
+// referenceEnd returns the end of the reference starting at start: a name
+// ("_" or digits) followed by .field and [index] selectors.
+func referenceEnd(s string, start int) int {
+	i := start
+	switch {
+	case i < len(s) && s[i] == '_':
+		i++
+	case i < len(s) && isDigit(s[i]):
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	default:
+		return start
+	}
+
+	for i < len(s) {
+		switch {
+		case s[i] == '.' && i+1 < len(s) && isFieldChar(s[i+1]):
+			i++
+			for i < len(s) && isFieldChar(s[i]) {
+				i++
+			}
+		case s[i] == '[':
+			j := i + 1
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			if j == i+1 || j >= len(s) || s[j] != ']' {
+				return i
+			}
+			i = j + 1
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// Lookup selects a field of a JSON value with a path of .field and [index]
+// selectors. An empty path selects the value itself.
+func Lookup(value interface{}, path string) (interface{}, error) {
+	for path != "" {
+		switch path[0] {
+		case '.':
+			end := 1
+			for end < len(path) && isFieldChar(path[end]) {
+				end++
+			}
+			field := path[1:end]
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot select .%s of a non-object", field)
+			}
+			if value, ok = obj[field]; !ok {
+				return nil, fmt.Errorf("no field %q", field)
+			}
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path")
+			}
+			index, err := strconv.Atoi(path[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", path[1:end])
+			}
+			arr, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index a non-array")
+			}
+			if index < 0 || index >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range (length %d)", index, len(arr))
+			}
+			value = arr[index]
+			path = path[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return value, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isFieldChar(c byte) bool {
+	return c == '_' || c == '-' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
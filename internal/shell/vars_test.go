@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVars_Expand(t *testing.T) {
+	var vars Vars
+	vars.Add(json.RawMessage(`{"structuredContent":{"items":[{"id":7},{"id":9}]}}`))
+	vars.Add(json.RawMessage(`{"content":[{"type":"text","text":"hello"}]}`))
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"id": $1.structuredContent.items[1].id}`, `{"id": 9}`},
+		{`{"text": $_.content[0].text}`, `{"text": "hello"}`},
+		{`{"items": $1.structuredContent.items}`, `{"items": [{"id":7},{"id":9}]}`},
+		{`{"price": "$1", "note": "a \"$2\" b"}`, `{"price": "$1", "note": "a \"$2\" b"}`},
+		{`$2`, `{"content":[{"text":"hello","type":"text"}]}`},
+		{`{"cost": $}`, `{"cost": $}`},
+	}
+	for _, tt := range tests {
+		got, err := vars.Expand(tt.input)
+		if err != nil {
+			t.Errorf("Expand(%s) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%s) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestVars_ExpandErrors(t *testing.T) {
+	var vars Vars
+	if _, err := vars.Expand(`{"a": $_}`); err == nil {
+		t.Error("expected error for $_ without results")
+	}
+
+	vars.Add(json.RawMessage(`{"items":[]}`))
+	for _, input := range []string{`$2`, `$1.missing`, `$1.items[0]`, `$1.items.x`} {
+		if _, err := vars.Expand(input); err == nil {
+			t.Errorf("Expand(%s): expected error", input)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package terminal
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -16,6 +19,58 @@ func GetWidth() int {
 	return width
 }
 
+// IsInteractive returns true if both stdin and stderr are attached to a terminal,
+// so the user can be prompted.
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// Confirm asks a yes/no question on stderr and returns true if the user answers yes.
+func Confirm(question string) bool {
+	ok, _ := YesNo(bufio.NewReader(os.Stdin), os.Stderr, question, false)
+	return ok
+}
+
+// YesNo asks a yes/no question on out and reads answers from in until it
+// gets one. An empty answer picks def. It returns io.EOF if the input ends
+// before an answer.
+func YesNo(in *bufio.Reader, out io.Writer, question string, def bool) (bool, error) {
+	options := "[y/N]"
+	if def {
+		options = "[Y/n]"
+	}
+	for {
+		fmt.Fprintf(out, "%s %s ", question, options)
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				fmt.Fprintln(out)
+			}
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(out, "answer y or n")
+	}
+}
+
+// ReadPassword prompts on stderr and reads a line from stdin without echoing it.
+func ReadPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
 // WrapText wraps text to the specified width with the given indent for continuation lines.
 // The first line has no indent, subsequent lines are indented.
 func WrapText(text string, width int, indent string) string {
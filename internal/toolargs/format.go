@@ -0,0 +1,357 @@
+package toolargs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/juanibiapina/mcpli/internal/schema"
+	"github.com/titanous/json5"
+	"gopkg.in/yaml.v3"
+)
+
+// Input formats of tool arguments.
+const (
+	FormatAuto  = "auto"
+	FormatJSON  = "json"
+	FormatJSON5 = "json5"
+	FormatYAML  = "yaml"
+)
+
+// Formats lists the input formats accepted by Parse.
+var Formats = []string{FormatAuto, FormatJSON, FormatJSON5, FormatYAML}
+
+// Parse converts arguments written in the given format to JSON. With
+// FormatAuto, valid JSON is kept as is, input starting with "{" or "[" is
+// read as JSON5 (or as YAML flow syntax if that fails) and anything else as
+// YAML. A single YAML line that isn't a valid mapping, such as
+// "query: milk, limit: 5", is read as comma-separated key/value pairs.
+//
+// JSON5 and YAML scalars given for string properties of the input schema,
+// which may be nil, keep their literal text: "zip: 01234" gives "01234" when
+// zip is a string. Numbers keep their literal text otherwise too, when it is
+// a valid JSON number.
+func Parse(data []byte, format string, inputSchema json.RawMessage) (json.RawMessage, error) {
+	var root map[string]interface{}
+	if len(inputSchema) > 0 {
+		json.Unmarshal(inputSchema, &root)
+	}
+
+	switch format {
+	case FormatJSON:
+		var test interface{}
+		if err := json.Unmarshal(data, &test); err != nil {
+			return nil, fmt.Errorf("invalid JSON arguments: %w", err)
+		}
+		return json.RawMessage(data), nil
+	case FormatJSON5:
+		v, err := parseJSON5(data, root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON5 arguments: %w", err)
+		}
+		return encode(v)
+	case FormatYAML:
+		v, err := parseYAMLArguments(data, root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML arguments: %w", err)
+		}
+		return encode(v)
+	case FormatAuto, "":
+	default:
+		return nil, fmt.Errorf("unknown input format %q (use %s)", format, strings.Join(Formats, ", "))
+	}
+
+	if json.Valid(data) {
+		return json.RawMessage(data), nil
+	}
+	trimmed := strings.TrimSpace(strings.TrimPrefix(string(data), "\ufeff"))
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		v, err := parseJSON5(data, root)
+		if err != nil {
+			if v, yamlErr := parseYAMLArguments(data, root); yamlErr == nil {
+				return encode(v)
+			}
+			return nil, fmt.Errorf("invalid JSON5 arguments: %w", err)
+		}
+		return encode(v)
+	}
+	v, err := parseYAMLArguments(data, root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML arguments: %w", err)
+	}
+	return encode(v)
+}
+
+// parseJSON5 parses a JSON5 document. Numbers are decoded as their literal
+// text and converted by coerce.
+func parseJSON5(data []byte, s map[string]interface{}) (interface{}, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	// Unmarshal checks the whole document, including trailing comments; the
+	// decoder then reads its value with the literal text of numbers.
+	if err := json5.Unmarshal(data, new(interface{})); err != nil {
+		var syntaxErr *json5.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := position(data, syntaxErr.Offset)
+			return nil, fmt.Errorf("line %d, column %d: %v", line, column, err)
+		}
+		return nil, err
+	}
+	dec := json5.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return coerce(v, s)
+}
+
+// position returns the line and column of the last byte read before a
+// syntax error.
+func position(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n') - 1
+	return line, column
+}
+
+// coerce converts JSON5 values for the schema: numbers and booleans become
+// their literal text for string properties, and numbers that aren't valid
+// JSON, such as 0x1F or .5, are converted to JSON numbers.
+func coerce(v interface{}, s map[string]interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			c, err := coerce(item, property(s, k))
+			if err != nil {
+				return nil, err
+			}
+			x[k] = c
+		}
+	case []interface{}:
+		for i, item := range x {
+			c, err := coerce(item, items(s))
+			if err != nil {
+				return nil, err
+			}
+			x[i] = c
+		}
+	case json5.Number:
+		if schema.Type(s) == "string" {
+			return string(x), nil
+		}
+		return number(string(x))
+	case bool:
+		if schema.Type(s) == "string" {
+			return strconv.FormatBool(x), nil
+		}
+	case float64:
+		// Infinity and NaN, which are decoded as floats even with UseNumber
+		return nil, fmt.Errorf("%v can't be represented in JSON", x)
+	}
+	return v, nil
+}
+
+// jsonNumber matches number literals that are valid JSON.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// number converts a JSON5 number literal to a JSON number, keeping the
+// literal when it is valid JSON.
+func number(text string) (interface{}, error) {
+	if jsonNumber.MatchString(text) {
+		return json.Number(text), nil
+	}
+	unsigned := strings.TrimLeft(text, "+-")
+	if strings.HasPrefix(unsigned, "0x") || strings.HasPrefix(unsigned, "0X") {
+		n, err := strconv.ParseInt(unsigned[2:], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", text)
+		}
+		if strings.HasPrefix(text, "-") {
+			n = -n
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s", text)
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%s can't be represented in JSON", text)
+	}
+	return f, nil
+}
+
+// parseYAMLArguments parses a YAML document, falling back to
+// comma-separated key/value pairs for a single line.
+func parseYAMLArguments(data []byte, s map[string]interface{}) (interface{}, error) {
+	v, err := parseYAML(data, s)
+	if err == nil {
+		return v, nil
+	}
+	if line := strings.TrimSpace(string(data)); line != "" && !strings.Contains(line, "\n") {
+		if v, ok, lineErr := parseYAMLLine(line, s); ok {
+			return v, lineErr
+		}
+	}
+	return nil, err
+}
+
+// parseYAML parses a single YAML document.
+func parseYAML(data []byte, s map[string]interface{}) (interface{}, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, yamlError(err)
+	}
+	if err := dec.Decode(new(yaml.Node)); err != io.EOF {
+		if err != nil {
+			return nil, yamlError(err)
+		}
+		return nil, errors.New("multiple documents are not supported")
+	}
+	return yamlValue(&doc, s)
+}
+
+// yamlError removes the "yaml: " prefix of YAML errors.
+func yamlError(err error) error {
+	return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+// yamlValue converts a YAML node to a JSON value. Scalars for string
+// properties keep their literal text, and numbers keep theirs when it is
+// valid JSON.
+func yamlValue(node *yaml.Node, s map[string]interface{}) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0], s)
+	case yaml.AliasNode:
+		return yamlValue(node.Alias, s)
+	case yaml.MappingNode:
+		obj := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be strings", key.Line)
+			}
+			if _, exists := obj[key.Value]; exists {
+				return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+			}
+			v, err := yamlValue(node.Content[i+1], property(s, key.Value))
+			if err != nil {
+				return nil, err
+			}
+			obj[key.Value] = v
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		arr := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			v, err := yamlValue(item, items(s))
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}
+
+	tag := node.ShortTag()
+	if tag == "!!null" {
+		return nil, nil
+	}
+	if schema.Type(s) == "string" {
+		return node.Value, nil
+	}
+	switch tag {
+	case "!!bool", "!!int", "!!float":
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return nil, yamlError(err)
+		}
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return nil, fmt.Errorf("line %d: %s can't be represented in JSON", node.Line, node.Value)
+		}
+		if tag != "!!bool" && jsonNumber.MatchString(node.Value) {
+			return json.Number(node.Value), nil
+		}
+		return v, nil
+	}
+	return node.Value, nil
+}
+
+// yamlKey matches the start of a key/value pair in a single line of
+// comma-separated pairs.
+var yamlKey = regexp.MustCompile(`(?:^|,)\s*([A-Za-z_][\w.-]*):(?:\s+|$)`)
+
+// parseYAMLLine reads a single line of key/value pairs separated by commas,
+// such as "query: milk, limit: 5". A comma only separates pairs when a key
+// follows it, so "brand: a, b" is one pair. It returns false if the line
+// doesn't start with a key.
+func parseYAMLLine(line string, s map[string]interface{}) (interface{}, bool, error) {
+	matches := yamlKey.FindAllStringSubmatchIndex(line, -1)
+	if len(matches) == 0 || matches[0][0] != 0 {
+		return nil, false, nil
+	}
+
+	obj := map[string]interface{}{}
+	for i, m := range matches {
+		key := line[m[2]:m[3]]
+		end := len(line)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		if _, exists := obj[key]; exists {
+			return nil, true, fmt.Errorf("duplicate key %q", key)
+		}
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(strings.TrimSpace(line[m[1]:end])), &node); err != nil {
+			return nil, true, fmt.Errorf("%s: %v", key, yamlError(err))
+		}
+		v, err := yamlValue(&node, property(s, key))
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %v", key, err)
+		}
+		obj[key] = v
+	}
+	return obj, true, nil
+}
+
+// property returns the schema of an object schema's property, if any.
+func property(s map[string]interface{}, name string) map[string]interface{} {
+	properties, _ := s["properties"].(map[string]interface{})
+	prop, _ := properties[name].(map[string]interface{})
+	return prop
+}
+
+// items returns the schema of an array schema's items, if any.
+func items(s map[string]interface{}) map[string]interface{} {
+	item, _ := s["items"].(map[string]interface{})
+	return item
+}
+
+// encode returns the JSON encoding of a parsed value, without escaping
+// HTML characters.
+func encode(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
@@ -0,0 +1,139 @@
+package toolargs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{"json kept as is", FormatAuto, `{"query": "milk"}`, `{"query": "milk"}`},
+		{"json5", FormatAuto, `{query: 'milk', limit: 5, /* max */ tags: ["a", "b",],} // done`, `{"limit":5,"query":"milk","tags":["a","b"]}`},
+		{"json5 numbers", FormatJSON5, `{a: 0x1F, b: .5, c: 5., d: +1, e: -2e3}`, `{"a":31,"b":0.5,"c":5,"d":1,"e":-2e3}`},
+		{"json5 strings", FormatJSON5, `{s: 'it\'s "quoted"\nline \u0041é', t: "a\
+b"}`, `{"s":"it's \"quoted\"\nline Aé","t":"ab"}`},
+		{"yaml flow mapping", FormatAuto, `{query: milk, limit: 5}`, `{"limit":5,"query":"milk"}`},
+		{"yaml line", FormatAuto, `query: milk, limit: 5`, `{"limit":5,"query":"milk"}`},
+		{"yaml single entry", FormatAuto, `title: Hello, world`, `{"title":"Hello, world"}`},
+		{"yaml scalars", FormatYAML, "a: 1\nb: 1.50\nc: true\nd: null\ne: ~\nf: yes\ng: '007'\nh: \"x\\ty\"\ni: 0x10\nj: <b>",
+			`{"a":1,"b":1.50,"c":true,"d":null,"e":null,"f":"yes","g":"007","h":"x\ty","i":16,"j":"<b>"}`},
+		{"yaml nested", FormatAuto, `
+# Search arguments
+query: milk   # the product
+filter:
+  min: 1
+  tags:
+  - organic
+  - fresh
+sort:
+  - field: price
+    order: asc
+  - field: name
+`, `{"filter":{"min":1,"tags":["organic","fresh"]},"query":"milk","sort":[{"field":"price","order":"asc"},{"field":"name"}]}`},
+		{"yaml block scalars", FormatAuto, `---
+body: |
+  line one
+    indented
+
+  line three
+summary: >-
+  folded
+  text
+
+  new paragraph
+keep: |+
+  kept
+
+next: 1
+`, `{"body":"line one\n  indented\n\nline three\n","keep":"kept\n\n","next":1,"summary":"folded text\nnew paragraph"}`},
+		{"yaml multi-line flow", FormatYAML, "items: [1,\n  2, 3]\nname: 'it''s'", `{"items":[1,2,3],"name":"it's"}`},
+		{"yaml folded plain scalar", FormatYAML, "text: a long\n  sentence\nn: 2", `{"n":2,"text":"a long sentence"}`},
+		{"yaml top-level sequence", FormatYAML, "- a\n- - b\n  - c\n-\n  d: 1", `["a",["b","c"],{"d":1}]`},
+		{"html not escaped", FormatYAML, "q: a & b", `{"q":"a & b"}`},
+		{"yaml anchors", FormatYAML, "a: &x 1\nb: *x", `{"a":1,"b":1}`},
+		{"yaml line with comma in value", FormatAuto, `q: milk, brand: a, b`, `{"brand":"a, b","q":"milk"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input), tt.format, nil)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{"invalid json", FormatJSON, `{query: milk}`, "invalid JSON arguments"},
+		{"json5 unquoted value", FormatJSON5, `{query: milk}`, "invalid JSON5 arguments: line 1, column 9"},
+		{"json5 infinity", FormatJSON5, `{a: -Infinity}`, "can't be represented in JSON"},
+		{"json5 unterminated", FormatAuto, `{"a": [1, 2}`, "invalid JSON5 arguments"},
+		{"yaml bad indentation", FormatYAML, "a:\n  b: 1\n c: 2", "did not find expected key"},
+		{"yaml duplicate key", FormatYAML, "a: 1\na: 2", `line 2: duplicate key "a"`},
+		{"yaml infinity", FormatYAML, "a: .inf", "can't be represented in JSON"},
+		{"yaml multiple documents", FormatYAML, "a: 1\n---\nb: 2", "multiple documents are not supported"},
+		{"yaml nested colon", FormatYAML, "a: b: c\nd: 1", "mapping values are not allowed"},
+		{"unknown format", "toml", "a = 1", `unknown input format "toml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input), tt.format, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Schema(t *testing.T) {
+	inputSchema := []byte(`{
+		"type": "object",
+		"properties": {
+			"version": {"type": "string"},
+			"zip": {"type": ["string", "null"]},
+			"flag": {"type": "string"},
+			"count": {"type": "integer"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"address": {"properties": {"zip": {"type": "string"}}}
+		}
+	}`)
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+	}{
+		{"yaml strings keep their text", FormatYAML, "version: 1.10\nzip: 01234\nflag: true\ncount: 0x10",
+			`{"count":16,"flag":"true","version":"1.10","zip":"01234"}`},
+		{"yaml null stays null", FormatYAML, "zip: null", `{"zip":null}`},
+		{"yaml nested", FormatYAML, "tags: [1.0, 2]\naddress:\n  zip: 00100", `{"address":{"zip":"00100"},"tags":["1.0","2"]}`},
+		{"yaml line", FormatAuto, "version: 2.0, count: 3", `{"count":3,"version":"2.0"}`},
+		{"json5", FormatJSON5, "{version: 1.10, flag: false, count: 0x10}", `{"count":16,"flag":"false","version":"1.10"}`},
+		{"json kept as is", FormatAuto, `{"version": 1.10}`, `{"version": 1.10}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input), tt.format, inputSchema)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package toolargs reads tool arguments written as JSON, JSON5 or YAML from
+// the command line, files and stdin, and converts per-property flag values
+// to the types of a tool's input schema.
+package toolargs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/juanibiapina/mcpli/internal/schema"
+)
+
+// Property is a top-level property of a tool's input schema.
+type Property struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	// Binary is set for string properties holding base64-encoded data
+	Binary bool
+}
+
+// Properties returns the top-level properties of an input schema, sorted by
+// name.
+func Properties(inputSchema json.RawMessage) []Property {
+	var s struct {
+		Properties map[string]struct {
+			Type            interface{} `json:"type"`
+			Description     string      `json:"description"`
+			Format          string      `json:"format"`
+			ContentEncoding string      `json:"contentEncoding"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(inputSchema, &s); err != nil {
+		return nil
+	}
+
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	props := make([]Property, 0, len(s.Properties))
+	for name, p := range s.Properties {
+		prop := Property{
+			Name:        name,
+			Type:        schema.TypeName(p.Type),
+			Description: p.Description,
+			Required:    required[name],
+		}
+		prop.Binary = prop.Type == "string" &&
+			(strings.EqualFold(p.ContentEncoding, "base64") || p.Format == "byte" || p.Format == "binary")
+		props = append(props, prop)
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+// Loader loads values that may refer to a file or to stdin. Stdin can be
+// read only once per command.
+type Loader struct {
+	stdin     io.Reader
+	stdinUsed bool
+}
+
+// NewLoader creates a loader reading "@-" references from stdin.
+func NewLoader(stdin io.Reader) *Loader {
+	return &Loader{stdin: stdin}
+}
+
+// Load returns the data a value refers to: the contents of the file for
+// "@path", stdin for "@-", and the value itself otherwise, with a leading
+// "@@" unescaped to "@". It reports whether the data was read from a file
+// or stdin.
+func (l *Loader) Load(value string) ([]byte, bool, error) {
+	switch {
+	case strings.HasPrefix(value, "@@"):
+		return []byte(value[1:]), false, nil
+	case value == "@-":
+		if l.stdinUsed {
+			return nil, false, errors.New("stdin can only be read once")
+		}
+		l.stdinUsed = true
+		data, err := io.ReadAll(l.stdin)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, true, nil
+	case strings.HasPrefix(value, "@"):
+		data, err := os.ReadFile(value[1:])
+		if err != nil {
+			return nil, false, err
+		}
+		return data, true, nil
+	}
+	return []byte(value), false, nil
+}
+
+// Value converts a flag value to the type of a property. Data read from a
+// file or stdin is kept as is for string properties, except that it is
+// base64-encoded for binary properties and when it isn't valid UTF-8; for
+// other types it is parsed like a flag value.
+func (l *Loader) Value(prop Property, value string) (interface{}, error) {
+	data, loaded, err := l.Load(value)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %w", prop.Name, err)
+	}
+
+	if loaded && (prop.Type == "string" || !utf8.Valid(data)) {
+		if prop.Binary || !utf8.Valid(data) {
+			return base64.StdEncoding.EncodeToString(data), nil
+		}
+		return string(data), nil
+	}
+
+	text := string(data)
+	if loaded {
+		text = strings.TrimSpace(text)
+	}
+	v, err := Coerce(prop.Type, text)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %w", prop.Name, err)
+	}
+	return v, nil
+}
+
+// Coerce converts text to a value of the given JSON Schema type. Objects
+// and arrays can be written in any format accepted by Parse. Text for a
+// schema without a type is parsed as JSON when possible, and is a string
+// otherwise.
+func Coerce(typ, s string) (interface{}, error) {
+	switch typ {
+	case "string":
+		return s, nil
+	case "integer":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return n, nil
+	case "boolean":
+		switch strings.ToLower(s) {
+		case "y", "yes", "true", "1":
+			return true, nil
+		case "n", "no", "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", s)
+	case "null":
+		return nil, nil
+	case "object", "array":
+		data, err := Parse([]byte(s), FormatAuto, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", typ, err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", typ, err)
+		}
+		return v, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v, nil
+	}
+	return s, nil
+}
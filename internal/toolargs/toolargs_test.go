@@ -0,0 +1,120 @@
+package toolargs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProperties(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string", "description": "Search text"},
+			"limit": {"type": ["integer", "null"]},
+			"image": {"type": "string", "contentEncoding": "base64"},
+			"blob": {"type": "string", "format": "byte"}
+		},
+		"required": ["query"]
+	}`)
+
+	want := []Property{
+		{Name: "blob", Type: "string", Binary: true},
+		{Name: "image", Type: "string", Binary: true},
+		{Name: "limit", Type: "integer"},
+		{Name: "query", Type: "string", Description: "Search text", Required: true},
+	}
+	if got := Properties(schema); !reflect.DeepEqual(got, want) {
+		t.Errorf("Properties() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoader_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args.json")
+	os.WriteFile(path, []byte(`{"a": 1}`), 0600)
+
+	l := NewLoader(strings.NewReader("from stdin"))
+	tests := []struct {
+		value  string
+		want   string
+		loaded bool
+	}{
+		{"plain", "plain", false},
+		{"@" + path, `{"a": 1}`, true},
+		{"@-", "from stdin", true},
+		{"@@handle", "@handle", false},
+	}
+	for _, tt := range tests {
+		data, loaded, err := l.Load(tt.value)
+		if err != nil || string(data) != tt.want || loaded != tt.loaded {
+			t.Errorf("Load(%q) = %q, %v, %v, want %q, %v", tt.value, data, loaded, err, tt.want, tt.loaded)
+		}
+	}
+
+	if _, _, err := l.Load("@-"); err == nil || !strings.Contains(err.Error(), "only be read once") {
+		t.Errorf("second Load(@-) error = %v", err)
+	}
+	if _, _, err := l.Load("@" + filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestLoader_Value(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "doc.txt")
+	os.WriteFile(text, []byte("line one\nline two\n"), 0600)
+	binary := filepath.Join(dir, "image.png")
+	os.WriteFile(binary, []byte{0x89, 'P', 'N', 'G', 0xff}, 0600)
+	number := filepath.Join(dir, "limit")
+	os.WriteFile(number, []byte("42\n"), 0600)
+	object := filepath.Join(dir, "filter.json")
+	os.WriteFile(object, []byte(`{"min": 1}`), 0600)
+
+	tests := []struct {
+		prop  Property
+		value string
+		want  interface{}
+	}{
+		{Property{Name: "q", Type: "string"}, "milk", "milk"},
+		{Property{Name: "q", Type: "string"}, "42", "42"},
+		{Property{Name: "n", Type: "integer"}, "42", int64(42)},
+		{Property{Name: "n", Type: "number"}, "1.5", 1.5},
+		{Property{Name: "b", Type: "boolean"}, "true", true},
+		{Property{Name: "o", Type: "object"}, `{"a": [1]}`, map[string]interface{}{"a": []interface{}{float64(1)}}},
+		{Property{Name: "o", Type: "object"}, "min: 1, max: 2", map[string]interface{}{"min": float64(1), "max": float64(2)}},
+		{Property{Name: "x"}, "7", float64(7)},
+		{Property{Name: "x"}, "seven", "seven"},
+		{Property{Name: "body", Type: "string"}, "@" + text, "line one\nline two\n"},
+		{Property{Name: "image", Type: "string", Binary: true}, "@" + text, "bGluZSBvbmUKbGluZSB0d28K"},
+		{Property{Name: "data", Type: "string"}, "@" + binary, "iVBOR/8="},
+		{Property{Name: "n", Type: "integer"}, "@" + number, int64(42)},
+		{Property{Name: "o", Type: "object"}, "@" + object, map[string]interface{}{"min": float64(1)}},
+	}
+	for _, tt := range tests {
+		got, err := NewLoader(nil).Value(tt.prop, tt.value)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Value(%s, %q) = %#v, %v, want %#v", tt.prop.Name, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoader_ValueErrors(t *testing.T) {
+	l := NewLoader(nil)
+	for _, tt := range []struct {
+		prop  Property
+		value string
+		want  string
+	}{
+		{Property{Name: "limit", Type: "integer"}, "ten", `--limit: "ten" is not an integer`},
+		{Property{Name: "exact", Type: "boolean"}, "maybe", `--exact: "maybe" is not a boolean`},
+		{Property{Name: "filter", Type: "object"}, "{", "--filter: invalid object"},
+	} {
+		_, err := l.Value(tt.prop, tt.value)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Value(%q) error = %v, want %q", tt.value, err, tt.want)
+		}
+	}
+}
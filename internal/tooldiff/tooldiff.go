@@ -0,0 +1,279 @@
+// Package tooldiff compares two versions of a server's tool list and
+// classifies input schema changes as breaking or compatible.
+package tooldiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+)
+
+// maxDepth is how deep Compare descends into nested properties and array
+// items; changes below it are not reported.
+const maxDepth = 16
+
+// Change is one difference in a tool's input schema. Path names the property,
+// with nested properties joined by "." and array items written as "[]"; it is
+// empty for changes to the schema root.
+type Change struct {
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+	Breaking bool   `json:"breaking"`
+}
+
+// ToolChange lists the changes to a tool present in both versions.
+type ToolChange struct {
+	Name               string   `json:"name"`
+	DescriptionChanged bool     `json:"description_changed,omitempty"`
+	Changes            []Change `json:"changes,omitempty"`
+}
+
+// Diff is the difference between two tool lists.
+type Diff struct {
+	Added   []string     `json:"added,omitempty"`
+	Removed []string     `json:"removed,omitempty"`
+	Changed []ToolChange `json:"changed,omitempty"`
+}
+
+// Empty reports whether the tool lists are equivalent.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Breaking reports whether any change can break existing callers: a removed
+// tool, a removed property, a new required property or an incompatible type.
+func (d *Diff) Breaking() bool {
+	if len(d.Removed) > 0 {
+		return true
+	}
+	for _, tc := range d.Changed {
+		for _, c := range tc.Changes {
+			if c.Breaking {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Compare returns the difference from old to new. Tools are matched by name,
+// so a renamed tool shows up as removed and added.
+func Compare(old, new []config.Tool) *Diff {
+	oldTools := byName(old)
+	newTools := byName(new)
+	d := &Diff{}
+
+	for _, name := range slices.Sorted(maps.Keys(newTools)) {
+		if _, ok := oldTools[name]; !ok {
+			d.Added = append(d.Added, name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(oldTools)) {
+		before := oldTools[name]
+		after, ok := newTools[name]
+		if !ok {
+			d.Removed = append(d.Removed, name)
+			continue
+		}
+
+		tc := ToolChange{
+			Name:               name,
+			DescriptionChanged: before.Description != after.Description,
+			Changes:            compareSchemas("", decode(before.InputSchema), decode(after.InputSchema), 0),
+		}
+		if tc.DescriptionChanged || len(tc.Changes) > 0 {
+			d.Changed = append(d.Changed, tc)
+		}
+	}
+
+	return d
+}
+
+// compareSchemas compares two property schemas at path.
+func compareSchemas(path string, old, new map[string]interface{}, depth int) []Change {
+	if depth > maxDepth {
+		return nil
+	}
+	var changes []Change
+
+	oldType, newType := typeSet(old), typeSet(new)
+	if len(oldType) > 0 && len(newType) > 0 && !reflect.DeepEqual(oldType, newType) {
+		changes = append(changes, Change{
+			Path:     path,
+			Message:  fmt.Sprintf("type changed from %s to %s", strings.Join(oldType, "|"), strings.Join(newType, "|")),
+			Breaking: !subset(oldType, newType),
+		})
+	}
+
+	changes = append(changes, compareEnums(path, old["enum"], new["enum"])...)
+	changes = append(changes, compareProperties(path, old, new, depth)...)
+
+	oldItems, _ := old["items"].(map[string]interface{})
+	newItems, _ := new["items"].(map[string]interface{})
+	if oldItems != nil && newItems != nil {
+		changes = append(changes, compareSchemas(path+"[]", oldItems, newItems, depth+1)...)
+	}
+
+	return changes
+}
+
+// compareProperties compares the properties and required lists of two
+// object schemas.
+func compareProperties(path string, old, new map[string]interface{}, depth int) []Change {
+	oldProps, _ := old["properties"].(map[string]interface{})
+	newProps, _ := new["properties"].(map[string]interface{})
+	oldRequired, newRequired := stringSet(old["required"]), stringSet(new["required"])
+	var changes []Change
+
+	for _, name := range slices.Sorted(maps.Keys(oldProps)) {
+		if _, ok := newProps[name]; !ok {
+			changes = append(changes, Change{Path: join(path, name), Message: "property removed", Breaking: true})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(newProps)) {
+		p := join(path, name)
+		oldProp, existed := oldProps[name]
+		switch {
+		case !existed && newRequired[name]:
+			changes = append(changes, Change{Path: p, Message: "required property added", Breaking: true})
+		case !existed:
+			changes = append(changes, Change{Path: p, Message: "optional property added"})
+		default:
+			if newRequired[name] && !oldRequired[name] {
+				changes = append(changes, Change{Path: p, Message: "property is now required", Breaking: true})
+			}
+			if oldRequired[name] && !newRequired[name] {
+				changes = append(changes, Change{Path: p, Message: "property is no longer required"})
+			}
+			before, _ := oldProp.(map[string]interface{})
+			after, _ := newProps[name].(map[string]interface{})
+			changes = append(changes, compareSchemas(p, before, after, depth+1)...)
+		}
+	}
+
+	return changes
+}
+
+// compareEnums reports removed enum values as breaking and added ones as
+// compatible. A property that gains an enum is breaking too.
+func compareEnums(path string, old, new interface{}) []Change {
+	oldValues, _ := old.([]interface{})
+	newValues, _ := new.([]interface{})
+	if old == nil && new == nil {
+		return nil
+	}
+	if old == nil {
+		return []Change{{Path: path, Message: fmt.Sprintf("values restricted to %s", formatValues(newValues)), Breaking: true}}
+	}
+	if new == nil {
+		return []Change{{Path: path, Message: "values no longer restricted"}}
+	}
+
+	var changes []Change
+	if removed := missing(oldValues, newValues); len(removed) > 0 {
+		changes = append(changes, Change{Path: path, Message: fmt.Sprintf("enum values removed: %s", formatValues(removed)), Breaking: true})
+	}
+	if added := missing(newValues, oldValues); len(added) > 0 {
+		changes = append(changes, Change{Path: path, Message: fmt.Sprintf("enum values added: %s", formatValues(added))})
+	}
+	return changes
+}
+
+func byName(tools []config.Tool) map[string]config.Tool {
+	m := make(map[string]config.Tool, len(tools))
+	for _, t := range tools {
+		m[t.Name] = t
+	}
+	return m
+}
+
+func decode(raw json.RawMessage) map[string]interface{} {
+	var schema map[string]interface{}
+	json.Unmarshal(raw, &schema)
+	return schema
+}
+
+// typeSet returns the sorted types of a schema's "type" keyword. "integer"
+// values are also numbers, so a change from integer to number is compatible.
+func typeSet(schema map[string]interface{}) []string {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// subset reports whether every type in a is accepted by b.
+func subset(a, b []string) bool {
+	accepted := map[string]bool{}
+	for _, t := range b {
+		accepted[t] = true
+	}
+	for _, t := range a {
+		if !accepted[t] && !(t == "integer" && accepted["number"]) {
+			return false
+		}
+	}
+	return true
+}
+
+func stringSet(v interface{}) map[string]bool {
+	set := map[string]bool{}
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+// missing returns the values of a that are not in b.
+func missing(a, b []interface{}) []interface{} {
+	var out []interface{}
+	for _, v := range a {
+		found := false
+		for _, w := range b {
+			if reflect.DeepEqual(v, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func formatValues(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
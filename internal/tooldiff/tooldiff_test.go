@@ -0,0 +1,116 @@
+package tooldiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/juanibiapina/mcpli/internal/config"
+)
+
+func tool(name, schema string) config.Tool {
+	return config.Tool{Name: name, InputSchema: json.RawMessage(schema)}
+}
+
+func TestCompare_AddedAndRemovedTools(t *testing.T) {
+	old := []config.Tool{tool("search", `{}`), tool("get_cart", `{}`)}
+	new := []config.Tool{tool("search", `{}`), tool("checkout", `{}`)}
+
+	d := Compare(old, new)
+	if !reflect.DeepEqual(d.Added, []string{"checkout"}) || !reflect.DeepEqual(d.Removed, []string{"get_cart"}) {
+		t.Errorf("added %v, removed %v; want [checkout], [get_cart]", d.Added, d.Removed)
+	}
+	if len(d.Changed) != 0 {
+		t.Errorf("changed = %+v, want none", d.Changed)
+	}
+	if !d.Breaking() {
+		t.Error("removed tool should be breaking")
+	}
+}
+
+func TestCompare_Unchanged(t *testing.T) {
+	schema := `{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`
+	d := Compare([]config.Tool{tool("search", schema)}, []config.Tool{tool("search", schema)})
+	if !d.Empty() || d.Breaking() {
+		t.Errorf("Compare() = %+v, want empty", d)
+	}
+}
+
+func TestCompare_SchemaChanges(t *testing.T) {
+	old := tool("search", `{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string"},
+			"limit": {"type": "integer"},
+			"sort": {"type": "string", "enum": ["price", "name"]},
+			"legacy": {"type": "boolean"},
+			"filter": {"type": "object", "properties": {"brand": {"type": "string"}}}
+		},
+		"required": ["query"]
+	}`)
+	new := tool("search", `{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string"},
+			"limit": {"type": "number"},
+			"sort": {"type": "string", "enum": ["price", "rating"]},
+			"region": {"type": "string"},
+			"page": {"type": "integer"},
+			"filter": {"type": "object", "properties": {"brand": {"type": "array"}}}
+		},
+		"required": ["query", "region"]
+	}`)
+
+	d := Compare([]config.Tool{old}, []config.Tool{new})
+	if len(d.Changed) != 1 {
+		t.Fatalf("changed = %+v, want one tool", d.Changed)
+	}
+
+	want := []Change{
+		{Path: "legacy", Message: "property removed", Breaking: true},
+		{Path: "filter.brand", Message: "type changed from string to array", Breaking: true},
+		{Path: "limit", Message: "type changed from integer to number"},
+		{Path: "page", Message: "optional property added"},
+		{Path: "region", Message: "required property added", Breaking: true},
+		{Path: "sort", Message: `enum values removed: "name"`, Breaking: true},
+		{Path: "sort", Message: `enum values added: "rating"`},
+	}
+	if got := d.Changed[0].Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes =\n%+v\nwant\n%+v", got, want)
+	}
+	if !d.Breaking() {
+		t.Error("expected breaking diff")
+	}
+}
+
+func TestCompare_RequiredChanges(t *testing.T) {
+	old := tool("t", `{"properties":{"a":{"type":"string"},"b":{"type":"string"}},"required":["a"]}`)
+	new := tool("t", `{"properties":{"a":{"type":"string"},"b":{"type":"string"}},"required":["b"]}`)
+
+	d := Compare([]config.Tool{old}, []config.Tool{new})
+	want := []Change{
+		{Path: "a", Message: "property is no longer required"},
+		{Path: "b", Message: "property is now required", Breaking: true},
+	}
+	if got := d.Changed[0].Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompare_CompatibleChanges(t *testing.T) {
+	old := config.Tool{Name: "t", Description: "old", InputSchema: json.RawMessage(`{"properties":{"tags":{"type":"array","items":{"type":"string","enum":["a"]}}}}`)}
+	new := config.Tool{Name: "t", Description: "new", InputSchema: json.RawMessage(`{"properties":{"tags":{"type":"array","items":{"type":"string"}}}}`)}
+
+	d := Compare([]config.Tool{old}, []config.Tool{new})
+	if d.Breaking() {
+		t.Errorf("Compare() = %+v, want no breaking changes", d)
+	}
+	tc := d.Changed[0]
+	if !tc.DescriptionChanged {
+		t.Error("expected description change")
+	}
+	want := []Change{{Path: "tags[]", Message: "values no longer restricted"}}
+	if !reflect.DeepEqual(tc.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", tc.Changes, want)
+	}
+}
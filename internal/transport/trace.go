@@ -0,0 +1,243 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are always redacted from traces: the standard credential
+// headers and common API key headers, whether their value is configured
+// literally or from an environment variable.
+var sensitiveHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "Api-Key", "X-Auth-Token", "X-Access-Token", "Private-Token",
+	"X-Goog-Api-Key", "Ocp-Apim-Subscription-Key",
+}
+
+// sensitiveParams are OAuth form parameters and JSON fields holding secrets.
+var sensitiveParams = []string{"access_token", "refresh_token", "id_token", "client_secret", "code", "code_verifier", "device_code", "token"}
+
+// sensitiveJSON matches string values of sensitive JSON fields.
+var sensitiveJSON = regexp.MustCompile(`("(?:` + strings.Join(sensitiveParams, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// TraceOptions configures wire tracing.
+type TraceOptions struct {
+	// Unredacted disables redaction of secrets.
+	Unredacted bool
+}
+
+// Tracer writes HTTP requests and responses to a writer.
+type Tracer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	opts TraceOptions
+	seq  atomic.Int64
+}
+
+// NewTracer returns a tracer writing to w.
+func NewTracer(w io.Writer, opts TraceOptions) *Tracer {
+	return &Tracer{w: w, opts: opts}
+}
+
+// Wrap returns a transport that traces the requests sent through rt.
+// secretHeaders are redacted in addition to the credential headers.
+func (t *Tracer) Wrap(rt http.RoundTripper, secretHeaders []string) http.RoundTripper {
+	return &tracingTransport{tracer: t, next: rt, secretHeaders: secretHeaders}
+}
+
+// write writes a block of trace lines at once, so concurrent requests don't
+// interleave within a block.
+func (t *Tracer) write(lines []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, line := range lines {
+		fmt.Fprintln(t.w, line)
+	}
+}
+
+type tracingTransport struct {
+	tracer        *Tracer
+	next          http.RoundTripper
+	secretHeaders []string
+}
+
+// isSecretHeader returns true if the header value must be redacted.
+func (t *tracingTransport) isSecretHeader(name string) bool {
+	if t.tracer.opts.Unredacted {
+		return false
+	}
+	for _, h := range append(sensitiveHeaders, t.secretHeaders...) {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerLines formats headers in a stable order, redacting secrets.
+func (t *tracingTransport) headerLines(prefix string, header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		for _, value := range header[name] {
+			if t.isSecretHeader(name) {
+				value = redacted
+			}
+			lines = append(lines, fmt.Sprintf("%s%s: %s", prefix, name, value))
+		}
+	}
+	return lines
+}
+
+// redactBody hides secrets in a request or response body.
+func (t *Tracer) redactBody(contentType, body string) string {
+	if t.opts.Unredacted {
+		return body
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(body)
+		if err == nil {
+			for _, param := range sensitiveParams {
+				if values.Has(param) {
+					values.Set(param, redacted)
+				}
+			}
+			return values.Encode()
+		}
+	}
+	return sensitiveJSON.ReplaceAllString(body, `$1"`+redacted+`"`)
+}
+
+// redactURL hides secrets passed as query parameters.
+func (t *Tracer) redactURL(u *url.URL) string {
+	if t.opts.Unredacted || u.RawQuery == "" {
+		return u.String()
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = t.redactBody("application/x-www-form-urlencoded", u.RawQuery)
+	return redactedURL.String()
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tr := t.tracer
+	id := tr.seq.Add(1)
+	prefix := fmt.Sprintf("[%d] ", id)
+
+	lines := []string{fmt.Sprintf("%s> %s %s", prefix, req.Method, tr.redactURL(req.URL))}
+	lines = append(lines, t.headerLines(prefix+"> ", req.Header)...)
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		lines = append(lines, prefix+">")
+		for _, line := range strings.Split(tr.redactBody(req.Header.Get("Content-Type"), string(body)), "\n") {
+			lines = append(lines, prefix+"> "+line)
+		}
+	}
+	tr.write(lines)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		tr.write([]string{fmt.Sprintf("%s! %v (%s)", prefix, err, elapsed)})
+		return nil, err
+	}
+
+	lines = []string{fmt.Sprintf("%s< %s %s (%s)", prefix, resp.Proto, resp.Status, elapsed)}
+	lines = append(lines, t.headerLines(prefix+"< ", resp.Header)...)
+	lines = append(lines, prefix+"<")
+	tr.write(lines)
+
+	resp.Body = &tracingBody{
+		tracer:      tr,
+		prefix:      prefix,
+		contentType: resp.Header.Get("Content-Type"),
+		body:        resp.Body,
+		start:       start,
+	}
+	return resp, nil
+}
+
+// tracingBody logs a response body line by line as it is read, so server-sent
+// events are traced as they arrive.
+type tracingBody struct {
+	tracer      *Tracer
+	prefix      string
+	contentType string
+	body        io.ReadCloser
+	start       time.Time
+	pending     []byte
+	size        int
+	done        bool
+}
+
+func (b *tracingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.size += n
+	b.pending = append(b.pending, p[:n]...)
+	b.flushLines()
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *tracingBody) Close() error {
+	b.finish()
+	return b.body.Close()
+}
+
+// flushLines traces the complete lines read so far.
+func (b *tracingBody) flushLines() {
+	i := bytes.LastIndexByte(b.pending, '\n')
+	if i < 0 {
+		return
+	}
+	b.traceText(string(b.pending[:i]))
+	b.pending = b.pending[i+1:]
+}
+
+func (b *tracingBody) traceText(text string) {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(b.tracer.redactBody(b.contentType, text)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, b.prefix+"< "+strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	b.tracer.write(lines)
+}
+
+func (b *tracingBody) finish() {
+	if b.done {
+		return
+	}
+	b.done = true
+	if len(b.pending) > 0 {
+		b.traceText(string(b.pending))
+		b.pending = nil
+	}
+	elapsed := time.Since(b.start).Round(time.Millisecond)
+	b.tracer.write([]string{fmt.Sprintf("%s< (%d bytes in %s)", b.prefix, b.size, elapsed)})
+}
@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func traceRequest(t *testing.T, opts TraceOptions, req *http.Request, secretHeaders []string) string {
+	t.Helper()
+	var out bytes.Buffer
+	tracer := NewTracer(&out, opts)
+	client := &http.Client{Transport: tracer.Wrap(http.DefaultTransport, secretHeaders)}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return out.String()
+}
+
+func TestTracer_JSONRPCAndSSE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Set-Cookie", "session=abc")
+		io.WriteString(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n\n")
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","method":"tools/list","id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Api-Key", "secret-key")
+	req.Header.Set("X-Tenant-Secret", "secret-tenant")
+	req.Header.Set("X-Request-Id", "visible")
+
+	out := traceRequest(t, TraceOptions{}, req, []string{"x-tenant-secret"})
+
+	for _, want := range []string{
+		"> POST " + server.URL + "/mcp",
+		"> Authorization: [REDACTED]",
+		"> X-Api-Key: [REDACTED]",
+		"> X-Request-Id: visible",
+		"> X-Tenant-Secret: [REDACTED]",
+		`> {"jsonrpc":"2.0","method":"tools/list","id":1}`,
+		"< HTTP/1.1 200 OK",
+		"< Set-Cookie: [REDACTED]",
+		"< event: message",
+		`< data: {"jsonrpc":"2.0","id":1,"result":{}}`,
+		"bytes in",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace missing %q:\n%s", want, out)
+		}
+	}
+	for _, secret := range []string{"secret-token", "secret-key", "secret-tenant", "session=abc"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("trace contains secret %q:\n%s", secret, out)
+		}
+	}
+}
+
+func TestTracer_RedactsOAuthSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token":"at-123","refresh_token":"rt-456","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	body := "grant_type=authorization_code&code=auth-code&client_secret=cs-789&redirect_uri=http%3A%2F%2F127.0.0.1"
+	req, _ := http.NewRequest("POST", server.URL+"/token", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	out := traceRequest(t, TraceOptions{}, req, nil)
+	for _, secret := range []string{"at-123", "rt-456", "auth-code", "cs-789"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("trace contains secret %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"grant_type=authorization_code", `"token_type":"Bearer"`, `"access_token":"[REDACTED]"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTracer_Unredacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"access_token":"at-123"}`)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+
+	out := traceRequest(t, TraceOptions{Unredacted: true}, req, nil)
+	for _, want := range []string{"Bearer secret-token", "at-123"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("unredacted trace missing %q:\n%s", want, out)
+		}
+	}
+}
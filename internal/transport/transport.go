@@ -0,0 +1,227 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Options configures the HTTP transport used to talk to a server.
+type Options struct {
+	// Host is the server's host. PinnedSPKI is only enforced for it, so
+	// authorization servers on other hosts are verified as usual.
+	Host string
+	// CAFile is a PEM bundle of additional trusted certificate authorities.
+	CAFile string
+	// ClientCert and ClientKey are PEM files of the client certificate
+	// presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipVerify disables certificate verification.
+	InsecureSkipVerify bool
+	// MinTLSVersion is the minimum TLS version: 1.0, 1.1, 1.2 or 1.3.
+	MinTLSVersion string
+	// PinnedSPKI lists base64 SHA-256 hashes of the subject public key info
+	// of certificates trusted for Host, optionally prefixed with "sha256/".
+	// One certificate of a verified chain must match, or the server's own
+	// certificate with InsecureSkipVerify.
+	PinnedSPKI []string
+	// Proxy is the URL of the proxy for all requests: http://, https:// (HTTP
+	// CONNECT) or socks5://. When empty, HTTP_PROXY and HTTPS_PROXY are used.
+	Proxy string
+	// NoProxy lists hosts that bypass the proxy, in NO_PROXY format. When
+	// empty, NO_PROXY is used.
+	NoProxy string
+}
+
+// New returns an HTTP transport configured with the given options, based on
+// the default transport.
+// Requests for unix:// URLs are sent over a unix domain socket.
+func New(opts Options) (http.RoundTripper, error) {
+	cfg, err := tlsConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := proxyFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+	base := newTransport(cfg, proxy)
+	unix := &unixSocketTransport{base: base, unix: newUnixTransport()}
+	if len(opts.PinnedSPKI) == 0 {
+		return unix, nil
+	}
+
+	pins := make(map[string]bool)
+	for _, pin := range opts.PinnedSPKI {
+		pins[strings.TrimPrefix(pin, "sha256/")] = true
+	}
+	host := hostname(opts.Host)
+
+	pinnedConfig := cfg.Clone()
+	pinnedConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		// The server can send any certificate along with its own, so pins
+		// are matched against the verified chains only, or against the leaf
+		// when verification is disabled.
+		var certs []*x509.Certificate
+		if pinnedConfig.InsecureSkipVerify {
+			certs = cs.PeerCertificates[:min(1, len(cs.PeerCertificates))]
+		} else {
+			for _, chain := range cs.VerifiedChains {
+				certs = append(certs, chain...)
+			}
+		}
+		for _, cert := range certs {
+			if pins[SPKIFingerprint(cert)] {
+				return nil
+			}
+		}
+		return fmt.Errorf("certificate of %s does not match any pinned public key", host)
+	}
+
+	return &pinnedHostTransport{host: host, pinned: newTransport(pinnedConfig, proxy), other: unix}, nil
+}
+
+// pinnedHostTransport enforces public key pins for connections to one host.
+type pinnedHostTransport struct {
+	host   string
+	pinned http.RoundTripper
+	other  http.RoundTripper
+}
+
+func (t *pinnedHostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.EqualFold(req.URL.Hostname(), t.host) {
+		return t.pinned.RoundTrip(req)
+	}
+	return t.other.RoundTrip(req)
+}
+
+// newTransport clones the default transport with the given TLS configuration
+// and proxy.
+func newTransport(cfg *tls.Config, proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = cfg
+	t.Proxy = proxy
+	return t
+}
+
+// proxyFunc returns the proxy selection for the given options: the explicit
+// proxy, or the one from the environment, honoring NO_PROXY either way.
+func proxyFunc(opts Options) (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+	if opts.Proxy != "" {
+		parsed, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q (expected http, https, socks5 or socks5h)", parsed.Scheme)
+		}
+		cfg.HTTPProxy = opts.Proxy
+		cfg.HTTPSProxy = opts.Proxy
+	}
+	if opts.NoProxy != "" {
+		cfg.NoProxy = opts.NoProxy
+	}
+
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// ProxyURL returns the proxy used for requests to target, or nil for a direct
+// connection.
+func ProxyURL(opts Options, target *url.URL) (*url.URL, error) {
+	if target.Scheme == "unix" {
+		return nil, nil
+	}
+	proxy, err := proxyFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+	return proxy(&http.Request{URL: target})
+}
+
+// tlsConfig returns the TLS configuration for the given options, without pins.
+func tlsConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+
+	if opts.MinTLSVersion != "" {
+		version, err := ParseTLSVersion(opts.MinTLSVersion)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MinVersion = version
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2".
+func ParseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version %q (expected 1.0, 1.1, 1.2 or 1.3)", s)
+	}
+}
+
+// SPKIFingerprint returns the base64 SHA-256 hash of a certificate's subject
+// public key info, as used for pinning.
+func SPKIFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// hostname strips the port from a host.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
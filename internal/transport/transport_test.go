@@ -0,0 +1,324 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, opts Options, url string) error {
+	t.Helper()
+	rt, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	resp, err := (&http.Client{Transport: rt}).Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNew_DefaultRejectsUnknownCA(t *testing.T) {
+	server := newTLSServer(t)
+
+	if err := get(t, Options{}, server.URL); err == nil {
+		t.Error("expected certificate verification error")
+	}
+}
+
+func TestNew_CAFile(t *testing.T) {
+	server := newTLSServer(t)
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	if err := get(t, Options{CAFile: caFile}, server.URL); err != nil {
+		t.Errorf("request with CA file failed: %v", err)
+	}
+}
+
+func TestNew_CAFileWithoutCertificates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(path, []byte("not a certificate"), 0600)
+
+	if _, err := New(Options{CAFile: path}); err == nil {
+		t.Error("expected error for CA file without certificates")
+	}
+}
+
+func TestNew_InsecureSkipVerify(t *testing.T) {
+	server := newTLSServer(t)
+
+	if err := get(t, Options{InsecureSkipVerify: true}, server.URL); err != nil {
+		t.Errorf("request with insecure_skip_verify failed: %v", err)
+	}
+}
+
+func TestNew_PinnedSPKI(t *testing.T) {
+	server := newTLSServer(t)
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	host := strings.TrimPrefix(server.URL, "https://")
+	pin := SPKIFingerprint(server.Certificate())
+
+	if err := get(t, Options{Host: host, CAFile: caFile, PinnedSPKI: []string{"sha256/" + pin}}, server.URL); err != nil {
+		t.Errorf("request with matching pin failed: %v", err)
+	}
+
+	err := get(t, Options{Host: host, CAFile: caFile, PinnedSPKI: []string{"AAAA"}}, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("expected pin mismatch error, got %v", err)
+	}
+
+	// Pins only apply to the server host
+	if err := get(t, Options{Host: "other.example.com", CAFile: caFile, PinnedSPKI: []string{"AAAA"}}, server.URL); err != nil {
+		t.Errorf("pin for another host should not apply: %v", err)
+	}
+}
+
+func TestNew_PinnedSPKI_UnverifiedCertificate(t *testing.T) {
+	// The pinned certificate is sent by the server, but isn't part of its
+	// verified chain
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "pinned"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTLSServer(t)
+	server.TLS.Certificates[0].Certificate = append(server.TLS.Certificates[0].Certificate, der)
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	host := strings.TrimPrefix(server.URL, "https://")
+	pin := SPKIFingerprint(pinned)
+
+	err = get(t, Options{Host: host, CAFile: caFile, PinnedSPKI: []string{pin}}, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("expected pin mismatch error for a trusted server, got %v", err)
+	}
+	err = get(t, Options{Host: host, InsecureSkipVerify: true, PinnedSPKI: []string{pin}}, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("expected pin mismatch error without verification, got %v", err)
+	}
+
+	// Without verification, the server's own certificate can be pinned
+	leafPin := SPKIFingerprint(server.Certificate())
+	if err := get(t, Options{Host: host, InsecureSkipVerify: true, PinnedSPKI: []string{leafPin}}, server.URL); err != nil {
+		t.Errorf("request with pinned leaf failed: %v", err)
+	}
+}
+
+func TestNew_MinTLSVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	if err := get(t, Options{InsecureSkipVerify: true, MinTLSVersion: "1.2"}, server.URL); err != nil {
+		t.Errorf("TLS 1.2 request failed: %v", err)
+	}
+	if err := get(t, Options{InsecureSkipVerify: true, MinTLSVersion: "1.3"}, server.URL); err == nil {
+		t.Error("expected handshake failure with minimum TLS 1.3")
+	}
+}
+
+func TestNew_ClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mcpli-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := writePEM(t, "client.pem", "CERTIFICATE", der)
+	keyFile := writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER)
+
+	var gotCN string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	if err := get(t, Options{InsecureSkipVerify: true}, server.URL); err == nil {
+		t.Error("expected failure without client certificate")
+	}
+	if err := get(t, Options{InsecureSkipVerify: true, ClientCert: certFile, ClientKey: keyFile}, server.URL); err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	if gotCN != "mcpli-test" {
+		t.Errorf("server saw client certificate %q, want %q", gotCN, "mcpli-test")
+	}
+
+	if _, err := New(Options{ClientCert: certFile}); err == nil {
+		t.Error("expected error for client certificate without key")
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint16
+	}{
+		{"1.0", tls.VersionTLS10},
+		{"1.1", tls.VersionTLS11},
+		{"1.2", tls.VersionTLS12},
+		{"TLS1.3", tls.VersionTLS13},
+	}
+	for _, tt := range tests {
+		got, err := ParseTLSVersion(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTLSVersion(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseTLSVersion("2.0"); err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
+
+func TestNew_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	if err := get(t, Options{Proxy: proxy.URL}, "http://mcp.example.test/mcp"); err != nil {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://mcp.example.test/mcp" {
+		t.Errorf("proxy received %v, want the request for http://mcp.example.test/mcp", proxied)
+	}
+
+	// Hosts in NoProxy are not sent to the proxy
+	proxied = nil
+	err := get(t, Options{Proxy: proxy.URL, NoProxy: "example.test"}, "http://mcp.example.test/mcp")
+	if err == nil {
+		t.Error("expected direct connection to the unresolvable host to fail")
+	}
+	if len(proxied) != 0 {
+		t.Errorf("NoProxy host was proxied: %v", proxied)
+	}
+}
+
+func TestNew_InvalidProxy(t *testing.T) {
+	if _, err := New(Options{Proxy: "ftp://proxy.example.com"}); err == nil {
+		t.Error("expected error for unsupported proxy scheme")
+	}
+}
+
+func TestNew_UnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "mcp.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	var gotPath, gotHost string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotHost = r.URL.Path, r.Host
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	if err := get(t, Options{}, "unix://"+socketPath+"/mcp"); err != nil {
+		t.Fatalf("request over unix socket failed: %v", err)
+	}
+	if gotPath != "/mcp" || gotHost != "localhost" {
+		t.Errorf("server received path %q host %q, want /mcp and localhost", gotPath, gotHost)
+	}
+}
+
+func TestParseUnixURL(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantSocket string
+		wantPath   string
+	}{
+		{"unix:///run/mcp.sock", "/run/mcp.sock", "/"},
+		{"unix:///run/mcp.sock/mcp", "/run/mcp.sock", "/mcp"},
+		{"unix:///run/mcp", "/run/mcp", "/"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.input)
+		socket, path := ParseUnixURL(u)
+		if socket != tt.wantSocket || path != tt.wantPath {
+			t.Errorf("ParseUnixURL(%q) = %q, %q; want %q, %q", tt.input, socket, path, tt.wantSocket, tt.wantPath)
+		}
+	}
+}
+
+func TestProxyURL(t *testing.T) {
+	target, _ := url.Parse("https://mcp.example.com/mcp")
+
+	proxy, err := ProxyURL(Options{Proxy: "socks5://127.0.0.1:1080"}, target)
+	if err != nil || proxy == nil || proxy.Host != "127.0.0.1:1080" {
+		t.Errorf("ProxyURL() = %v, %v; want socks5://127.0.0.1:1080", proxy, err)
+	}
+
+	proxy, err = ProxyURL(Options{Proxy: "socks5://127.0.0.1:1080", NoProxy: "example.com"}, target)
+	if err != nil || proxy != nil {
+		t.Errorf("ProxyURL() for a NoProxy host = %v, %v; want nil", proxy, err)
+	}
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// socketPathKey is the request context key of the unix socket to dial.
+type socketPathKey struct{}
+
+// ParseUnixURL splits a unix:// server URL into the socket path and the HTTP
+// request path. unix:///run/mcp.sock requests "/" on the socket; an HTTP path
+// can follow a socket name ending in .sock, as in unix:///run/mcp.sock/mcp.
+func ParseUnixURL(u *url.URL) (socketPath, requestPath string) {
+	path := u.Path
+	if i := strings.Index(path, ".sock/"); i >= 0 {
+		return path[:i+len(".sock")], path[i+len(".sock"):]
+	}
+	return path, "/"
+}
+
+// unixSocketTransport sends requests for unix:// URLs over the unix domain
+// socket and all others through the base transport.
+type unixSocketTransport struct {
+	base http.RoundTripper
+	unix http.RoundTripper
+}
+
+func (t *unixSocketTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "unix" {
+		return t.base.RoundTrip(req)
+	}
+
+	socketPath, requestPath := ParseUnixURL(req.URL)
+	if socketPath == "" {
+		return nil, fmt.Errorf("unix URL %s has no socket path", req.URL)
+	}
+
+	// Connections are pooled by host, so give each socket its own
+	sum := sha256.Sum256([]byte(socketPath))
+	target := *req.URL
+	target.Scheme = "http"
+	target.Host = "unix-" + hex.EncodeToString(sum[:8])
+	target.Path = requestPath
+	target.RawPath = ""
+
+	out := req.Clone(context.WithValue(req.Context(), socketPathKey{}, socketPath))
+	out.URL = &target
+	out.Host = "localhost"
+	return t.unix.RoundTrip(out)
+}
+
+// newUnixTransport returns a transport that dials the unix socket given in
+// the request context.
+func newUnixTransport() *http.Transport {
+	var dialer net.Dialer
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			socketPath, _ := ctx.Value(socketPathKey{}).(string)
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+		MaxIdleConns: 10,
+	}
+}